@@ -2,10 +2,17 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/emware/aeyewire-mcp/src/analyzers"
 	"github.com/emware/aeyewire-mcp/src/models"
@@ -15,14 +22,30 @@ import (
 const (
 	VERSION     = "1.0.0"
 	SERVER_NAME = "aeyewire_mcp"
+
+	// DEFAULT_MAX_CONCURRENCY is the number of requests handled in parallel
+	// when MCP_MAX_CONCURRENCY is not set
+	DEFAULT_MAX_CONCURRENCY = 4
+
+	// DEFAULT_LOG_LEVEL is the minimum level of log notifications until the
+	// client sends logging/setLevel
+	DEFAULT_LOG_LEVEL = services.LOG_WARNING
 )
 
-// MCPRequest represents an incoming MCP request
+// SUPPORTED_PROTOCOL_VERSIONS lists the MCP revisions the server speaks,
+// newest first
+var SUPPORTED_PROTOCOL_VERSIONS = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// MCPRequest represents an incoming MCP request. It also carries the
+// client's responses to server-initiated requests, which have no method and
+// set Result or Error instead.
 type MCPRequest struct {
 	JSONRPC string                 `json:"jsonrpc"`
 	ID      interface{}            `json:"id"`
 	Method  string                 `json:"method"`
 	Params  map[string]interface{} `json:"params,omitempty"`
+	Result  json.RawMessage        `json:"result,omitempty"`
+	Error   *MCPError              `json:"error,omitempty"`
 }
 
 // MCPResponse represents an outgoing MCP response
@@ -33,6 +56,13 @@ type MCPResponse struct {
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification represents an outgoing MCP notification
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 // MCPError represents an MCP error
 type MCPError struct {
 	Code    int    `json:"code"`
@@ -44,6 +74,20 @@ type MCPServer struct {
 	llmService       *services.LLMService
 	languageDetector *services.LanguageDetector
 	analyzers        map[models.LanguageType]analyzers.SecurityAnalyzer
+	tools            *ToolRegistry
+	reports          *reportStore
+	scans            *scanManager
+
+	// watchInterval is how often subscribed files are checked for changes
+	watchInterval time.Duration
+
+	// slots limits how many requests are handled at a time across all
+	// sessions and transports
+	slots chan struct{}
+
+	// writeMu serializes writes to out so JSON lines never interleave
+	writeMu sync.Mutex
+	out     io.Writer
 }
 
 // NewMCPServer creates a new MCP server instance
@@ -55,6 +99,12 @@ func NewMCPServer() *MCPServer {
 		llmService:       llmService,
 		languageDetector: languageDetector,
 		analyzers:        make(map[models.LanguageType]analyzers.SecurityAnalyzer),
+		tools:            NewToolRegistry(),
+		reports:          newReportStore(MAX_STORED_REPORTS),
+		scans:            newScanManager(positiveIntFromEnv("MCP_SCAN_WORKERS", DEFAULT_SCAN_WORKERS)),
+		watchInterval:    FILE_WATCH_INTERVAL,
+		slots:            make(chan struct{}, maxConcurrencyFromEnv()),
+		out:              os.Stdout,
 	}
 
 	// Register analyzers
@@ -63,186 +113,623 @@ func NewMCPServer() *MCPServer {
 	server.analyzers[models.REACT_TYPESCRIPT] = analyzers.NewReactAnalyzer(llmService, models.REACT_TYPESCRIPT)
 	server.analyzers[models.REACT_JAVASCRIPT] = analyzers.NewReactAnalyzer(llmService, models.REACT_JAVASCRIPT)
 
+	server.registerTools()
+
 	return server
 }
 
+// maxConcurrencyFromEnv reads the request concurrency limit from MCP_MAX_CONCURRENCY
+func maxConcurrencyFromEnv() int {
+	return positiveIntFromEnv("MCP_MAX_CONCURRENCY", DEFAULT_MAX_CONCURRENCY)
+}
+
+// positiveIntFromEnv reads a positive integer setting, falling back to
+// defaultValue when it is unset or invalid
+func positiveIntFromEnv(name string, defaultValue int) int {
+	if value := os.Getenv(name); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			return n
+		}
+		fmt.Fprintf(os.Stderr, "Invalid %s %q, using %d\n", name, value, defaultValue)
+	}
+	return defaultValue
+}
+
 // Run starts the MCP server and processes stdio requests
 func (s *MCPServer) Run() {
-	scanner := bufio.NewScanner(os.Stdin)
+	s.serve(os.Stdin)
+}
+
+// serve reads newline-delimited messages from in. Each request is handled
+// on its own goroutine; responses are written as they complete and may be
+// out of order.
+func (s *MCPServer) serve(in io.Reader) {
+	scanner := bufio.NewScanner(in)
 	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024) // 10MB buffer for large code
 
+	sess := newSession("stdio", s.writeMessage)
+	var wg sync.WaitGroup
+
 	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "" {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		var request MCPRequest
-		if err := json.Unmarshal([]byte(line), &request); err != nil {
-			s.sendError(nil, -32700, fmt.Sprintf("Parse error: %v", err))
+		messages, batch, err := parseMessages(line)
+		if err != nil {
+			s.writeMessage(err)
 			continue
 		}
 
-		s.handleRequest(&request)
+		// process registers requests before the next line is read, so a
+		// following notifications/cancelled always finds them. The reader
+		// never blocks on the concurrency limit, so it keeps draining stdin
+		// while long analyses are running.
+		wait := s.process(sess, messages, batch, s.writeMessage)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reply := wait(); reply != nil {
+				s.writeMessage(reply)
+			}
+		}()
 	}
 
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
 	}
+
+	wg.Wait()
+	sess.close()
+}
+
+// parseMessages decodes a single JSON-RPC message or a batch array. It
+// returns the error response to send when the payload is not valid JSON or
+// is an empty batch.
+func parseMessages(data []byte) ([]MCPRequest, bool, *MCPResponse) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var rawMessages []json.RawMessage
+		if err := json.Unmarshal(data, &rawMessages); err != nil {
+			return nil, true, newErrorResponse(nil, -32700, fmt.Sprintf("Parse error: %v", err))
+		}
+		if len(rawMessages) == 0 {
+			return nil, true, newErrorResponse(nil, -32600, "Invalid Request: empty batch")
+		}
+
+		// Elements that are not objects are kept as invalid requests so
+		// they still get an error response in the batch
+		messages := make([]MCPRequest, len(rawMessages))
+		for i, raw := range rawMessages {
+			if json.Unmarshal(raw, &messages[i]) != nil {
+				messages[i] = MCPRequest{}
+			}
+		}
+		return messages, true, nil
+	}
+
+	var message MCPRequest
+	if err := json.Unmarshal(data, &message); err != nil {
+		return nil, false, newErrorResponse(nil, -32700, fmt.Sprintf("Parse error: %v", err))
+	}
+	return []MCPRequest{message}, false, nil
+}
+
+// hasRequests reports whether any of messages expects a response
+func hasRequests(messages []MCPRequest) bool {
+	for _, message := range messages {
+		if message.ID != nil || !isValidMessage(&message) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidMessage reports whether message is a well-formed JSON-RPC 2.0
+// request, notification or response
+func isValidMessage(message *MCPRequest) bool {
+	if message.JSONRPC != "2.0" {
+		return false
+	}
+	if message.Method == "" {
+		return message.ID != nil && (message.Result != nil || message.Error != nil)
+	}
+	return true
+}
+
+// process routes messages received together from one client. Responses to
+// server-initiated requests and notifications are handled immediately.
+// Requests are registered for cancellation before process returns and run
+// concurrently, with related notifications sent through notify. The
+// returned function waits for them and gives the reply to send: nil when
+// nothing must be answered, a single response, or an array for a batch.
+func (s *MCPServer) process(sess *session, messages []MCPRequest, batch bool, notify func(message interface{})) func() interface{} {
+	responses := make([]*MCPResponse, len(messages))
+	var wg sync.WaitGroup
+
+	for i := range messages {
+		message := &messages[i]
+
+		switch {
+		case !isValidMessage(message):
+			responses[i] = newErrorResponse(message.ID, -32600, "Invalid Request")
+
+		case message.Method == "":
+			sess.deliverResponse(message)
+
+		// Notifications are cheap and must never wait for a slot, otherwise
+		// a cancellation could be stuck behind the request it is cancelling
+		case message.ID == nil:
+			s.handleRequest(context.Background(), sess, message)
+
+		default:
+			ctx, done, ok := sess.trackRequest(message.ID)
+			if !ok {
+				responses[i] = newErrorResponse(message.ID, -32600, fmt.Sprintf("Invalid Request: request ID %v is already in use", message.ID))
+				continue
+			}
+			ctx = withNotifier(ctx, notify)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer done()
+				responses[i] = s.execute(ctx, sess, message)
+			}()
+		}
+	}
+
+	return func() interface{} {
+		wg.Wait()
+
+		replies := []*MCPResponse{}
+		for _, response := range responses {
+			if response != nil {
+				replies = append(replies, response)
+			}
+		}
+
+		switch {
+		case len(replies) == 0:
+			return nil
+		case batch:
+			return replies
+		default:
+			return replies[0]
+		}
+	}
+}
+
+// execute handles a tracked request once a concurrency slot is free. It
+// returns nil if the request was cancelled, since a cancelled request must
+// not produce a response.
+func (s *MCPServer) execute(ctx context.Context, sess *session, request *MCPRequest) *MCPResponse {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return nil
+	}
+
+	response := s.handleRequest(ctx, sess, request)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return response
 }
 
-// handleRequest processes an MCP request
-func (s *MCPServer) handleRequest(request *MCPRequest) {
+// handleRequest processes an MCP request and returns its response, or nil
+// when the request is a notification that must not be answered
+func (s *MCPServer) handleRequest(ctx context.Context, sess *session, request *MCPRequest) *MCPResponse {
+	var result interface{}
+	var mcpErr *MCPError
+
+	if token := progressToken(request); token != nil {
+		ctx = analyzers.WithProgress(ctx, progressNotifier(ctx, token))
+	}
+	ctx = s.sessionContext(ctx, sess)
+
+	// Before initialize only pings are answered
+	if !sess.isInitialized() && request.Method != "initialize" && request.Method != "ping" {
+		if request.ID == nil {
+			return nil
+		}
+		return newErrorResponse(request.ID, -32600, fmt.Sprintf("Server not initialized: %s sent before initialize", request.Method))
+	}
+
 	switch request.Method {
 	case "initialize":
-		s.handleInitialize(request)
+		result, mcpErr = s.handleInitialize(sess, request)
+	case "ping":
+		result = map[string]interface{}{}
+	case "notifications/initialized":
+		if sess.clientSupports("roots") {
+			go s.refreshRoots(sess)
+		}
+		return nil
+	case "notifications/roots/list_changed":
+		sess.invalidateRoots()
+		go s.refreshRoots(sess)
+		return nil
 	case "tools/list":
-		s.handleToolsList(request)
+		result, mcpErr = s.handleToolsList(request)
 	case "tools/call":
-		s.handleToolsCall(request)
+		result, mcpErr = s.handleToolsCall(ctx, request)
+	case "resources/list":
+		result, mcpErr = s.handleResourcesList(sess, request)
+	case "resources/templates/list":
+		result, mcpErr = s.handleResourceTemplatesList(request)
+	case "resources/read":
+		result, mcpErr = s.handleResourcesRead(ctx, request)
+	case "resources/subscribe":
+		result, mcpErr = s.handleResourcesSubscribe(ctx, sess, request)
+	case "resources/unsubscribe":
+		result, mcpErr = s.handleResourcesUnsubscribe(sess, request)
+	case "prompts/list":
+		result, mcpErr = s.handlePromptsList(request)
+	case "prompts/get":
+		result, mcpErr = s.handlePromptsGet(ctx, request)
+	case "completion/complete":
+		result, mcpErr = s.handleComplete(ctx, request)
+	case "logging/setLevel":
+		result, mcpErr = s.handleSetLogLevel(sess, request)
+	case "notifications/cancelled":
+		s.handleCancelled(sess, request)
+		return nil
 	default:
-		s.sendError(request.ID, -32601, fmt.Sprintf("Method not found: %s", request.Method))
+		mcpErr = &MCPError{Code: -32601, Message: fmt.Sprintf("Method not found: %s", request.Method)}
+	}
+
+	// Notifications never get a response, even for unknown methods
+	if request.ID == nil {
+		return nil
+	}
+
+	if mcpErr != nil {
+		return &MCPResponse{JSONRPC: "2.0", ID: request.ID, Error: mcpErr}
+	}
+	return newResponse(request.ID, result)
+}
+
+// sessionContext returns ctx with the session, its sampling fallback and its
+// logger attached. Notifications go through the notifier already on ctx.
+func (s *MCPServer) sessionContext(ctx context.Context, sess *session) context.Context {
+	if sess.clientSupports("sampling") {
+		ctx = services.WithSampler(ctx, s.samplingFallback(sess))
+	}
+	ctx = services.WithLogger(ctx, logNotifier(ctx, sess))
+	return withSession(ctx, sess)
+}
+
+// progressToken returns the _meta.progressToken of a request, or nil if the
+// client did not ask for progress notifications
+func progressToken(request *MCPRequest) interface{} {
+	meta, ok := request.Params["_meta"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return meta["progressToken"]
+}
+
+// progressNotifier returns a ProgressFunc that emits notifications/progress
+// for token. Updates that would not increase progress are dropped, since
+// units of a scan may complete out of order.
+func progressNotifier(ctx context.Context, token interface{}) analyzers.ProgressFunc {
+	send := notifierFrom(ctx)
+	var mu sync.Mutex
+	last := -1.0
+
+	return func(progress float64, total float64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if progress <= last || ctx.Err() != nil {
+			return
+		}
+		last = progress
+
+		send(MCPNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/progress",
+			Params: map[string]interface{}{
+				"progressToken": token,
+				"progress":      progress,
+				"total":         total,
+				"message":       message,
+			},
+		})
+	}
+}
+
+// logNotifier returns a Logger that emits notifications/message for entries
+// at or above the session's log level. Entries logged after the request is
+// cancelled are dropped.
+func logNotifier(ctx context.Context, sess *session) services.Logger {
+	send := notifierFrom(ctx)
+
+	return func(level services.LogLevel, logger string, message string) {
+		if !sess.logs(level) || ctx.Err() != nil {
+			return
+		}
+		send(MCPNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/message",
+			Params: map[string]interface{}{
+				"level":  level.String(),
+				"logger": logger,
+				"data":   message,
+			},
+		})
+	}
+}
+
+// handleSetLogLevel handles the logging/setLevel request
+func (s *MCPServer) handleSetLogLevel(sess *session, request *MCPRequest) (interface{}, *MCPError) {
+	levelName, _ := request.Params["level"].(string)
+	level, ok := services.ParseLogLevel(levelName)
+	if !ok {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid log level: %q", levelName)}
+	}
+
+	sess.setLogLevel(level)
+	return map[string]interface{}{}, nil
+}
+
+// handleCancelled handles the notifications/cancelled notification. Unknown
+// or already completed requests are ignored, as required by the protocol.
+func (s *MCPServer) handleCancelled(sess *session, request *MCPRequest) {
+	requestID, ok := request.Params["requestId"]
+	if !ok {
+		return
 	}
+	sess.cancelRequest(requestID)
 }
 
 // handleInitialize handles MCP initialize request
-func (s *MCPServer) handleInitialize(request *MCPRequest) {
+func (s *MCPServer) handleInitialize(sess *session, request *MCPRequest) (interface{}, *MCPError) {
+	requestedVersion, _ := request.Params["protocolVersion"].(string)
+	protocolVersion := negotiateProtocolVersion(requestedVersion)
+
+	capabilities, _ := request.Params["capabilities"].(map[string]interface{})
+	if !sess.initialize(protocolVersion, capabilities) {
+		return nil, &MCPError{Code: -32600, Message: "Session already initialized"}
+	}
+
 	result := map[string]interface{}{
-		"protocolVersion": "2024-11-05",
+		"protocolVersion": protocolVersion,
 		"serverInfo": map[string]interface{}{
 			"name":    SERVER_NAME,
 			"version": VERSION,
 		},
 		"capabilities": map[string]interface{}{
-			"tools": map[string]bool{},
+			"tools":       map[string]bool{},
+			"resources":   map[string]bool{"subscribe": true},
+			"prompts":     map[string]bool{},
+			"logging":     map[string]bool{},
+			"completions": map[string]bool{},
 		},
 	}
-	s.sendResponse(request.ID, result)
+	return result, nil
+}
+
+// negotiateProtocolVersion returns the client's requested version when it is
+// supported, and otherwise the latest version the server supports
+func negotiateProtocolVersion(requested string) string {
+	for _, version := range SUPPORTED_PROTOCOL_VERSIONS {
+		if version == requested {
+			return version
+		}
+	}
+	return SUPPORTED_PROTOCOL_VERSIONS[0]
 }
 
 // handleToolsList handles tools/list request
-func (s *MCPServer) handleToolsList(request *MCPRequest) {
-	tools := []map[string]interface{}{
-		{
-			"name":        "analyze_security",
-			"description": "Performs comprehensive security analysis on source code",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"code": map[string]interface{}{
-						"type":        "string",
-						"description": "Source code to analyze",
-					},
-					"file_path": map[string]interface{}{
-						"type":        "string",
-						"description": "File path for context and language detection (optional)",
-					},
-					"language": map[string]interface{}{
-						"type":        "string",
-						"description": "Programming language (csharp, react_typescript, react_javascript, java, auto)",
-						"enum":        []string{"csharp", "react_typescript", "react_javascript", "java", "auto"},
-					},
-				},
-				"required": []string{"code"},
-			},
-		},
-		{
-			"name":        "health_check",
-			"description": "Verifies service health and dependency availability",
-			"inputSchema": map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
-		{
-			"name":        "list_supported_languages",
-			"description": "Lists all supported programming languages and their metadata",
-			"inputSchema": map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
-			},
-		},
+func (s *MCPServer) handleToolsList(request *MCPRequest) (interface{}, *MCPError) {
+	cursor, _ := request.Params["cursor"].(string)
+	page, nextCursor, err := s.tools.Page(cursor)
+	if err != nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid params: %v", err)}
+	}
+
+	tools := []map[string]interface{}{}
+	for _, tool := range page {
+		tools = append(tools, tool.toMap())
 	}
 
 	result := map[string]interface{}{
 		"tools": tools,
 	}
-	s.sendResponse(request.ID, result)
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
+	return result, nil
 }
 
 // handleToolsCall handles tools/call request
-func (s *MCPServer) handleToolsCall(request *MCPRequest) {
+func (s *MCPServer) handleToolsCall(ctx context.Context, request *MCPRequest) (interface{}, *MCPError) {
 	toolName, ok := request.Params["name"].(string)
 	if !ok {
-		s.sendError(request.ID, -32602, "Invalid tool name")
-		return
+		return nil, &MCPError{Code: -32602, Message: "Invalid tool name"}
 	}
 
-	arguments, _ := request.Params["arguments"].(map[string]interface{})
+	tool, ok := s.tools.Get(toolName)
+	if !ok {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unknown tool: %s", toolName)}
+	}
 
-	switch toolName {
-	case "analyze_security":
-		s.handleAnalyzeSecurity(request.ID, arguments)
-	case "health_check":
-		s.handleHealthCheck(request.ID)
-	case "list_supported_languages":
-		s.handleListSupportedLanguages(request.ID)
-	default:
-		s.sendError(request.ID, -32602, fmt.Sprintf("Unknown tool: %s", toolName))
+	arguments, _ := request.Params["arguments"].(map[string]interface{})
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	if err := validateArguments(tool.InputSchema, arguments); err != nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid arguments for %s: %v", toolName, err)}
 	}
+
+	return tool.Handler(ctx, arguments)
 }
 
 // handleAnalyzeSecurity handles the analyze_security tool
-func (s *MCPServer) handleAnalyzeSecurity(requestID interface{}, args map[string]interface{}) {
+func (s *MCPServer) handleAnalyzeSecurity(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
 	code, ok := args["code"].(string)
 	if !ok || code == "" {
-		s.sendError(requestID, -32602, "Missing or invalid 'code' parameter")
-		return
+		return nil, &MCPError{Code: -32602, Message: "Missing or invalid 'code' parameter"}
 	}
 
 	filePath, _ := args["file_path"].(string)
 	languageStr, _ := args["language"].(string)
 
-	// Detect language
-	var language models.LanguageType
-	if languageStr != "" && languageStr != "auto" {
-		language = models.LanguageType(languageStr)
-	} else {
-		language = s.languageDetector.Detect(code, filePath)
-	}
-
-	// Check if language is supported
-	analyzer, ok := s.analyzers[language]
-	if !ok {
-		s.sendError(requestID, -32602, fmt.Sprintf("Unsupported language: %s", language))
-		return
+	// The code is passed inline, so file_path is only a label. It is linked
+	// as the report's source when it names a file inside the workspace roots.
+	sourcePath := ""
+	if filePath != "" {
+		if resolved, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), filePath); mcpErr == nil {
+			sourcePath = resolved
+		}
 	}
 
-	// Perform analysis
-	result, err := analyzer.Analyze(code, filePath)
-	if err != nil {
-		s.sendError(requestID, -32603, fmt.Sprintf("Analysis failed: %v", err))
-		return
+	report, mcpErr := s.analyzeAndStore(ctx, languageStr, code, filePath, sourcePath)
+	if mcpErr != nil {
+		return nil, mcpErr
 	}
 
-	// Format as markdown
-	baseAnalyzer := analyzers.NewBaseAnalyzer(language, s.llmService)
-	markdown := baseAnalyzer.FormatAsMarkdown(result)
-
 	response := map[string]interface{}{
 		"content": []map[string]interface{}{
 			{
 				"type": "text",
-				"text": markdown,
+				"text": report.Markdown,
+			},
+			{
+				"type": "text",
+				"text": fmt.Sprintf("Report saved as %s", reportURI(report.ID)),
 			},
 		},
+		"structuredContent": report.Result,
+	}
+
+	return response, nil
+}
+
+// handleAnalyzeFile handles the analyze_file tool. Files are read server-side
+// and analyzed one after another; a file that cannot be read or analyzed is
+// reported without failing the others.
+func (s *MCPServer) handleAnalyzeFile(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	paths := []string{}
+	if path, ok := args["path"].(string); ok && path != "" {
+		paths = append(paths, path)
+	}
+	if list, ok := args["paths"].([]interface{}); ok {
+		for _, path := range list {
+			paths = append(paths, path.(string))
+		}
+	}
+	if len(paths) == 0 {
+		return nil, &MCPError{Code: -32602, Message: "Missing 'path' or 'paths' parameter"}
+	}
+	languageStr, _ := args["language"].(string)
+
+	// Check every path first, so a single path outside the workspace
+	// rejects the call before any file is read
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		var mcpErr *MCPError
+		if resolved[i], mcpErr = s.workspacePath(ctx, sessionFrom(ctx), path); mcpErr != nil {
+			return nil, mcpErr
+		}
+	}
+
+	content := []map[string]interface{}{}
+	files := []scanFileResult{}
+	failed := 0
+	for i, path := range resolved {
+		code, err := readSourceFile(path)
+		if err != nil {
+			failed++
+			files = append(files, scanFileResult{FilePath: paths[i], Error: err.Error()})
+			content = append(content, map[string]interface{}{"type": "text", "text": fmt.Sprintf("Error reading %s: %v", paths[i], err)})
+			continue
+		}
+
+		report, mcpErr := s.analyzeAndStore(analyzers.ForUnit(ctx, i, len(resolved)), languageStr, code, paths[i], path)
+		if mcpErr != nil {
+			if ctx.Err() != nil {
+				return nil, mcpErr
+			}
+			failed++
+			files = append(files, scanFileResult{FilePath: paths[i], Error: mcpErr.Message})
+			content = append(content, map[string]interface{}{"type": "text", "text": fmt.Sprintf("Error analyzing %s: %s", paths[i], mcpErr.Message)})
+			continue
+		}
+
+		files = append(files, scanFileResult{FilePath: paths[i], Result: report.Result})
+		content = append(content,
+			map[string]interface{}{"type": "text", "text": report.Markdown},
+			map[string]interface{}{"type": "text", "text": fmt.Sprintf("Report saved as %s", reportURI(report.ID))},
+		)
+	}
+
+	response := map[string]interface{}{
+		"content":           content,
+		"structuredContent": map[string]interface{}{"files": files},
+	}
+	if failed == len(files) {
+		response["isError"] = true
+	}
+	return response, nil
+}
+
+// analyzeAndStore analyzes code with the analyzer for languageStr, detecting
+// the language when it is empty or "auto", and keeps the report so it can be
+// cited later without re-running the LLM. sourcePath is the resolved path of
+// the workspace file the code came from, if known.
+func (s *MCPServer) analyzeAndStore(ctx context.Context, languageStr string, code string, filePath string, sourcePath string) (*storedReport, *MCPError) {
+	// Detect language
+	language, analyzer, mcpErr := s.resolveAnalyzer(ctx, languageStr, code, filePath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	analyzers.ReportStage(ctx, analyzers.StageDetect, fmt.Sprintf("Language detected: %s", language))
+
+	// Perform analysis
+	result, err := analyzer.Analyze(ctx, code, filePath)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Analysis failed: %v", err)}
+	}
+
+	report := s.storeResult(sessionFrom(ctx).id, sourcePath, filePath, result)
+	analyzers.ReportStage(ctx, analyzers.StageFormat, "Report formatted")
+
+	return report, nil
+}
+
+// storeResult formats an analysis result as markdown and keeps it as a
+// report of the session, so its findings can be looked up by fingerprint
+func (s *MCPServer) storeResult(sessionID string, sourcePath string, filePath string, result *models.AnalysisResult) *storedReport {
+	baseAnalyzer := analyzers.NewBaseAnalyzer(result.Language, s.llmService)
+	return s.reports.addFile(sessionID, sourcePath, filePath, result, baseAnalyzer.FormatAsMarkdown(result))
+}
+
+// resolveAnalyzer picks the analyzer for an explicit language, or detects the
+// language from code and filePath when languageStr is empty or "auto"
+func (s *MCPServer) resolveAnalyzer(ctx context.Context, languageStr string, code string, filePath string) (models.LanguageType, analyzers.SecurityAnalyzer, *MCPError) {
+	var language models.LanguageType
+	if languageStr != "" && languageStr != "auto" {
+		language = models.LanguageType(languageStr)
+	} else {
+		language = s.languageDetector.DetectContext(ctx, code, filePath)
 	}
 
-	s.sendResponse(requestID, response)
+	// Check if language is supported
+	analyzer, ok := s.analyzers[language]
+	if !ok {
+		return language, nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unsupported language: %s", language)}
+	}
+	return language, analyzer, nil
 }
 
 // handleHealthCheck handles the health_check tool
-func (s *MCPServer) handleHealthCheck(requestID interface{}) {
+func (s *MCPServer) handleHealthCheck() (interface{}, *MCPError) {
 	llmHealthy, _ := s.llmService.HealthCheck()
 
 	llmStatus := "unavailable"
@@ -273,11 +760,11 @@ func (s *MCPServer) handleHealthCheck(requestID interface{}) {
 		},
 	}
 
-	s.sendResponse(requestID, response)
+	return response, nil
 }
 
 // handleListSupportedLanguages handles the list_supported_languages tool
-func (s *MCPServer) handleListSupportedLanguages(requestID interface{}) {
+func (s *MCPServer) handleListSupportedLanguages() (interface{}, *MCPError) {
 	languages := s.languageDetector.GetSupportedLanguages()
 
 	jsonData, _ := json.MarshalIndent(languages, "", "  ")
@@ -291,24 +778,21 @@ func (s *MCPServer) handleListSupportedLanguages(requestID interface{}) {
 		},
 	}
 
-	s.sendResponse(requestID, response)
+	return response, nil
 }
 
-// sendResponse sends an MCP response
-func (s *MCPServer) sendResponse(id interface{}, result interface{}) {
-	response := MCPResponse{
+// newResponse builds a successful MCP response
+func newResponse(id interface{}, result interface{}) *MCPResponse {
+	return &MCPResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Result:  result,
 	}
-
-	jsonData, _ := json.Marshal(response)
-	fmt.Println(string(jsonData))
 }
 
-// sendError sends an MCP error response
-func (s *MCPServer) sendError(id interface{}, code int, message string) {
-	response := MCPResponse{
+// newErrorResponse builds an MCP error response
+func newErrorResponse(id interface{}, code int, message string) *MCPResponse {
+	return &MCPResponse{
 		JSONRPC: "2.0",
 		ID:      id,
 		Error: &MCPError{
@@ -316,9 +800,19 @@ func (s *MCPServer) sendError(id interface{}, code int, message string) {
 			Message: message,
 		},
 	}
+}
 
-	jsonData, _ := json.Marshal(response)
-	fmt.Println(string(jsonData))
+// writeMessage serializes a message as a single JSON line on the server output
+func (s *MCPServer) writeMessage(message interface{}) {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding message: %v\n", err)
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	fmt.Fprintln(s.out, string(jsonData))
 }
 
 func main() {
@@ -343,13 +837,32 @@ func handleCommandLine() {
 	command := os.Args[1]
 
 	switch command {
+	case "serve":
+		runServe(os.Args[2:])
 	case "analyze":
 		if len(os.Args) < 3 {
 			fmt.Println("Error: Missing file path")
 			printUsage()
 			os.Exit(1)
 		}
-		analyzeFile(os.Args[2])
+		if info, err := os.Stat(os.Args[2]); err == nil && info.IsDir() {
+			analyzeDirectory(os.Args[2])
+		} else {
+			analyzeFile(os.Args[2])
+		}
+	case "diff":
+		diffCommand(os.Args[2:])
+	case "fix":
+		if len(os.Args) < 3 {
+			fmt.Println("Error: Missing file path")
+			printUsage()
+			os.Exit(1)
+		}
+		fixFile(os.Args[2])
+	case "rules":
+		rulesCommand(os.Args[2:])
+	case "report":
+		reportCommand(os.Args[2:])
 	case "health":
 		checkHealth()
 	case "languages":
@@ -367,22 +880,42 @@ func printUsage() {
 	fmt.Println("AeyeWire MCP Service")
 	fmt.Println("\nUsage:")
 	fmt.Println("  aeyewire_mcp                  # Run as MCP stdio server")
-	fmt.Println("  aeyewire_mcp analyze <file>   # Analyze a file")
+	fmt.Println("  aeyewire_mcp serve [--http <addr>]  # Run as MCP server (stdio, or Streamable HTTP on addr)")
+	fmt.Println("  aeyewire_mcp analyze <file|dir>  # Analyze a file, or every source file under a directory")
+	fmt.Println("  aeyewire_mcp diff [--staged|<base>..<head>]  # Analyze the lines changed in the working tree, the index or a commit range")
+	fmt.Println("  aeyewire_mcp fix <file>       # Print a patch fixing the issues found in a file")
+	fmt.Println("  aeyewire_mcp rules [language|rule]  # List the security rules, or describe one")
+	fmt.Println("  aeyewire_mcp report diff [--json] <before.json> <after.json>  # Compare two saved analysis results")
 	fmt.Println("  aeyewire_mcp health           # Check service health")
 	fmt.Println("  aeyewire_mcp languages        # List supported languages")
 	fmt.Println("  aeyewire_mcp version          # Show version")
 }
 
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	httpAddr := flags.String("http", "", "Serve the Streamable HTTP transport on this address (e.g. :8080) instead of stdio")
+	flags.Parse(args)
+
+	server := NewMCPServer()
+	if *httpAddr == "" {
+		server.Run()
+		return
+	}
+
+	if err := server.RunHTTP(*httpAddr); err != nil {
+		fmt.Printf("HTTP server failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func analyzeFile(filePath string) {
 	// Read file
-	codeBytes, err := os.ReadFile(filePath)
+	code, err := readSourceFile(filePath)
 	if err != nil {
 		fmt.Printf("Error reading file: %v\n", err)
 		os.Exit(1)
 	}
 
-	code := string(codeBytes)
-
 	// Create server and analyze
 	server := NewMCPServer()
 	language := server.languageDetector.Detect(code, filePath)
@@ -400,7 +933,7 @@ func analyzeFile(filePath string) {
 
 	fmt.Printf("Analyzing %s as %s...\n\n", filePath, language)
 
-	result, err := analyzer.Analyze(code, filePath)
+	result, err := analyzer.Analyze(context.Background(), code, filePath)
 	if err != nil {
 		fmt.Printf("Analysis failed: %v\n", err)
 		os.Exit(1)
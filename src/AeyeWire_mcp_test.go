@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// newFakeLLM starts an LMStudio stand-in that answers every chat completion
+// with content, and points the LLM service configuration at it
+func newFakeLLM(t *testing.T, content string) *httptest.Server {
+	t.Helper()
+
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	t.Cleanup(llm.Close)
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+	return llm
+}
+
+// newTestSession creates an initialized session that drops notifications
+func newTestSession() *session {
+	sess := newSession("test", nil)
+	sess.initialize(SUPPORTED_PROTOCOL_VERSIONS[0], nil)
+	return sess
+}
+
+// callMethod sends a single request straight to the dispatcher
+func callMethod(t *testing.T, server *MCPServer, method string, params map[string]interface{}) *MCPResponse {
+	t.Helper()
+
+	request := &MCPRequest{JSONRPC: "2.0", ID: 1, Method: method, Params: params}
+	return server.handleRequest(context.Background(), newTestSession(), request)
+}
+
+// stdioClient drives serve over pipes, as an MCP client would over stdio
+type stdioClient struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines *bufio.Scanner
+}
+
+// startStdio runs server on pipes until the test ends
+func startStdio(t *testing.T, server *MCPServer) *stdioClient {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	server.out = outWriter
+
+	go server.serve(inReader)
+	t.Cleanup(func() { inWriter.Close() })
+
+	return &stdioClient{t: t, in: inWriter, lines: bufio.NewScanner(outReader)}
+}
+
+// send writes one line to the server
+func (c *stdioClient) send(line string) {
+	c.t.Helper()
+	if _, err := fmt.Fprintln(c.in, line); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// next reads the next message written by the server
+func (c *stdioClient) next() map[string]json.RawMessage {
+	c.t.Helper()
+
+	if !c.lines.Scan() {
+		c.t.Fatal("server closed its output")
+	}
+	var message map[string]json.RawMessage
+	if err := json.Unmarshal(c.lines.Bytes(), &message); err != nil {
+		c.t.Fatalf("invalid JSON line %q: %v", c.lines.Text(), err)
+	}
+	return message
+}
+
+// nextIgnoringLogs reads the next message that is not a log notification
+func (c *stdioClient) nextIgnoringLogs() map[string]json.RawMessage {
+	c.t.Helper()
+
+	for {
+		message := c.next()
+		if string(message["method"]) != `"notifications/message"` {
+			return message
+		}
+	}
+}
+
+// initialize performs the initialize handshake with the given capabilities
+func (c *stdioClient) initialize(capabilities string) map[string]json.RawMessage {
+	c.t.Helper()
+
+	c.send(`{"jsonrpc":"2.0","id":"init","method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":` + capabilities + `}}`)
+	response := c.next()
+	c.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	return response
+}
+
+func TestServeConcurrentRequests(t *testing.T) {
+	// The LLM holds the first analysis until the test releases it
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		started <- struct{}{}
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": "[]"}},
+			},
+		})
+	}))
+	var releaseOnce sync.Once
+	unblock := func() { releaseOnce.Do(func() { close(release) }) }
+	t.Cleanup(llm.Close)
+	t.Cleanup(unblock)
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+
+	server := NewMCPServer()
+	server.slots = make(chan struct{}, 2)
+	client := startStdio(t, server)
+	client.initialize(`{}`)
+
+	// A serial loop would never read past the blocked call, so responses are
+	// read in the background and waited for with a deadline
+	messages := make(chan map[string]json.RawMessage, 8)
+	go func() {
+		for {
+			messages <- client.nextIgnoringLogs()
+		}
+	}()
+	next := func() map[string]json.RawMessage {
+		t.Helper()
+		select {
+		case message := <-messages:
+			return message
+		case <-time.After(5 * time.Second):
+			t.Fatal("no response while the analysis is blocked")
+			return nil
+		}
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","language":"java"}}}`)
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the analysis never reached the LLM")
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	client.send(`{"jsonrpc":"2.0","id":"three","method":"no/such/method"}`)
+	client.send(`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"list_supported_languages"}}`)
+
+	// Responses may arrive in any order and are matched by id
+	responses := make(map[string]map[string]json.RawMessage)
+	for i := 0; i < 3; i++ {
+		response := next()
+		responses[string(response["id"])] = response
+	}
+	if responses["1"] != nil {
+		t.Fatal("the blocked analysis answered before it was released")
+	}
+	for _, id := range []string{"2", "4"} {
+		if responses[id]["result"] == nil {
+			t.Errorf("request %s failed: %s", id, responses[id]["error"])
+		}
+	}
+	var mcpErr MCPError
+	json.Unmarshal(responses[`"three"`]["error"], &mcpErr)
+	if mcpErr.Code != -32601 {
+		t.Errorf("expected method not found for request three, got %+v", responses[`"three"`])
+	}
+
+	unblock()
+	if response := next(); string(response["id"]) != "1" || response["result"] == nil {
+		t.Errorf("expected the released analysis to succeed, got %v", response)
+	}
+}
+
+func TestServeParseError(t *testing.T) {
+	server := NewMCPServer()
+	out := &bytes.Buffer{}
+	server.out = out
+
+	server.serve(strings.NewReader("{not json}\n"))
+
+	var response MCPResponse
+	if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.ID != nil || response.Error == nil || response.Error.Code != -32700 {
+		t.Errorf("expected parse error response, got %s", out.String())
+	}
+}
+
+func TestServeCancelledRequest(t *testing.T) {
+	aborted := make(chan struct{})
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body must be drained for the server to notice the client hanging up
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer llm.Close()
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{}`)
+
+	client.send(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","language":"java"}}}`)
+	time.Sleep(100 * time.Millisecond)
+	client.send(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"file closed"}}`)
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("LLM request was not aborted")
+	}
+
+	// The next message must answer the ping, not the cancelled request
+	client.send(`{"jsonrpc":"2.0","id":8,"method":"ping"}`)
+	if response := client.nextIgnoringLogs(); string(response["id"]) != "8" {
+		t.Errorf("expected no response for a cancelled request, got %v", response)
+	}
+}
+
+func TestServeProgressNotifications(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":3}]`)
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{}`)
+
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","language":"java"},"_meta":{"progressToken":"scan-1"}}}`)
+
+	var progress []float64
+	for {
+		message := client.next()
+		if message["method"] == nil {
+			if message["result"] == nil {
+				t.Fatalf("expected a successful response, got %v", message)
+			}
+			break
+		}
+
+		var params struct {
+			ProgressToken string  `json:"progressToken"`
+			Progress      float64 `json:"progress"`
+			Total         float64 `json:"total"`
+		}
+		json.Unmarshal(message["params"], &params)
+		if params.ProgressToken != "scan-1" || params.Total != analyzers.StageCount {
+			t.Errorf("unexpected progress notification: %s", message["params"])
+		}
+		progress = append(progress, params.Progress)
+	}
+
+	if len(progress) != analyzers.StageCount {
+		t.Fatalf("expected %d progress notifications, got %v", analyzers.StageCount, progress)
+	}
+	for i, p := range progress {
+		if p != float64(i+1) {
+			t.Errorf("progress[%d] = %v, want %d", i, p, i+1)
+		}
+	}
+}
+
+func TestAnalyzeSecurityStructuredContent(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":3}]`)
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_security",
+		"arguments": map[string]interface{}{"code": "public class A {}", "language": "java"},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_security failed: %s", response.Error.Message)
+	}
+
+	// Round-trip through JSON as a client would see it
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		Content           []map[string]interface{} `json:"content"`
+		StructuredContent models.AnalysisResult    `json:"structuredContent"`
+	}
+	if err := json.Unmarshal(jsonData, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Content) == 0 || result.Content[0]["type"] != "text" {
+		t.Error("expected the markdown report as text content")
+	}
+
+	structured := result.StructuredContent
+	if structured.Language != models.JAVA || len(structured.Issues) != 1 {
+		t.Fatalf("unexpected structuredContent: %+v", structured)
+	}
+	if structured.Issues[0].Severity != models.HIGH || structured.Issues[0].LineNumber != 3 {
+		t.Errorf("unexpected issue: %+v", structured.Issues[0])
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	client := startStdio(t, NewMCPServer())
+
+	// Only pings are answered before initialize
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if response := client.next(); response["error"] == nil {
+		t.Errorf("expected tools/list before initialize to fail, got %v", response)
+	}
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"ping"}`)
+	if response := client.next(); string(response["result"]) != "{}" {
+		t.Errorf("expected an empty ping result, got %v", response)
+	}
+
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(client.initialize(`{}`)["result"], &result)
+	if result.ProtocolVersion != "2025-06-18" {
+		t.Errorf("expected the requested protocol version, got %s", result.ProtocolVersion)
+	}
+
+	// Unknown notifications are ignored; the next message answers initialize
+	client.send(`{"jsonrpc":"2.0","method":"notifications/unknown"}`)
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"initialize","params":{}}`)
+	if response := client.next(); string(response["id"]) != "3" || response["error"] == nil {
+		t.Errorf("expected a second initialize to fail, got %v", response)
+	}
+}
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		requested string
+		expected  string
+	}{
+		{"2024-11-05", "2024-11-05"},
+		{"2025-03-26", "2025-03-26"},
+		{"2099-01-01", SUPPORTED_PROTOCOL_VERSIONS[0]},
+		{"", SUPPORTED_PROTOCOL_VERSIONS[0]},
+	}
+
+	for _, tt := range tests {
+		if version := negotiateProtocolVersion(tt.requested); version != tt.expected {
+			t.Errorf("negotiateProtocolVersion(%q) = %s, want %s", tt.requested, version, tt.expected)
+		}
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{}`)
+
+	client.send(`[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/list"},42]`)
+
+	if !client.lines.Scan() {
+		t.Fatal("server closed its output")
+	}
+	var responses []MCPResponse
+	if err := json.Unmarshal(client.lines.Bytes(), &responses); err != nil {
+		t.Fatalf("expected a batch response, got %s", client.lines.Text())
+	}
+
+	// Two requests plus one invalid element; the notification is not answered
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d: %s", len(responses), client.lines.Text())
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != -32600 {
+		t.Errorf("expected invalid request for the non-object element, got %+v", responses[2])
+	}
+
+	client.send(`[]`)
+	if response := client.next(); response["error"] == nil {
+		t.Errorf("expected an error for an empty batch, got %v", response)
+	}
+
+	// A batch of notifications produces no output; the next line answers the ping
+	client.send(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+	client.send(`{"jsonrpc":"2.0","id":9,"method":"ping"}`)
+	if response := client.next(); string(response["id"]) != "9" {
+		t.Errorf("expected the ping response, got %v", response)
+	}
+}
+
+func TestLoggingNotifications(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{}`)
+
+	client.send(`{"jsonrpc":"2.0","id":1,"method":"logging/setLevel","params":{"level":"verbose"}}`)
+	if response := client.next(); response["error"] == nil {
+		t.Errorf("expected an invalid log level to be rejected, got %v", response)
+	}
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"logging/setLevel","params":{"level":"info"}}`)
+	if response := client.next(); string(response["result"]) != "{}" {
+		t.Fatalf("logging/setLevel failed: %v", response)
+	}
+
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","file_path":"A.java"}}}`)
+
+	loggers := make(map[string]bool)
+	for {
+		message := client.next()
+		if message["method"] == nil {
+			break
+		}
+		if string(message["method"]) != `"notifications/message"` {
+			continue
+		}
+
+		var params struct {
+			Level  string `json:"level"`
+			Logger string `json:"logger"`
+			Data   string `json:"data"`
+		}
+		json.Unmarshal(message["params"], &params)
+		if params.Level == "debug" {
+			t.Errorf("debug entry sent at info level: %s", params.Data)
+		}
+		loggers[params.Logger] = true
+	}
+
+	for _, logger := range []string{"detector", "llm"} {
+		if !loggers[logger] {
+			t.Errorf("expected log notifications from %s, got %v", logger, loggers)
+		}
+	}
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":2}]`)
+	workspace := newScanWorkspace(t, "src/A.java")
+	os.WriteFile(filepath.Join(workspace, "src", "B.cs"), []byte("\xEF\xBB\xBFusing System;\r\nclass B {}\r\n"), 0o644)
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_file",
+		"arguments": map[string]interface{}{"paths": []interface{}{"src/A.java", "src/B.cs", "src/Missing.java"}},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_file failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		IsError           bool `json:"isError"`
+		StructuredContent struct {
+			Files []scanFileResult `json:"files"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	files := result.StructuredContent.Files
+	if result.IsError || len(files) != 3 {
+		t.Fatalf("unexpected analyze_file result: %s", jsonData)
+	}
+	if files[0].Result == nil || files[0].Result.Language != models.JAVA {
+		t.Errorf("expected A.java to be analyzed as Java, got %+v", files[0])
+	}
+	if files[1].Result == nil || files[1].Result.Language != models.CSHARP {
+		t.Errorf("expected B.cs to be analyzed as C#, got %+v", files[1])
+	}
+	if files[2].Result != nil || files[2].Error == "" {
+		t.Errorf("expected an error for the missing file, got %+v", files[2])
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_file",
+		"arguments": map[string]interface{}{"paths": []interface{}{"src/A.java", "../../etc/passwd"}},
+	})
+	if response.Error == nil {
+		t.Error("expected a path outside the workspace to reject the call")
+	}
+}
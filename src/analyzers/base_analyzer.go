@@ -1,6 +1,7 @@
 package analyzers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -15,12 +16,15 @@ import (
 type BaseSecurityAnalyzer struct {
 	Language   models.LanguageType
 	LLMService *services.LLMService
+	// Rules are the checks of the language analyzer embedding this one
+	Rules []models.SecurityRule
 }
 
 // SecurityAnalyzer interface that all analyzers must implement
 type SecurityAnalyzer interface {
-	Analyze(code string, filePath string) (*models.AnalysisResult, error)
+	Analyze(ctx context.Context, code string, filePath string) (*models.AnalysisResult, error)
 	GetSecurityRulesPrompt() string
+	GetRules() []models.SecurityRule
 }
 
 // NewBaseAnalyzer creates a new base analyzer
@@ -31,6 +35,11 @@ func NewBaseAnalyzer(language models.LanguageType, llmService *services.LLMServi
 	}
 }
 
+// GetRules returns the rules the analyzer checks for
+func (ba *BaseSecurityAnalyzer) GetRules() []models.SecurityRule {
+	return ba.Rules
+}
+
 // PreprocessCode removes comments while maintaining line structure
 func (ba *BaseSecurityAnalyzer) PreprocessCode(code string, language models.LanguageType) string {
 	switch language {
@@ -41,11 +50,15 @@ func (ba *BaseSecurityAnalyzer) PreprocessCode(code string, language models.Lang
 	}
 }
 
-// removeComments removes single-line and multi-line comments
+// removeComments removes single-line and multi-line comments. Multi-line
+// comments are replaced by the newlines they spanned, so the LLM's line
+// numbers still match the original code.
 func (ba *BaseSecurityAnalyzer) removeComments(code string) string {
 	// Remove multi-line comments (/* */ and /** */)
 	multiLineComment := regexp.MustCompile(`/\*[\s\S]*?\*/`)
-	code = multiLineComment.ReplaceAllString(code, "")
+	code = multiLineComment.ReplaceAllStringFunc(code, func(comment string) string {
+		return strings.Repeat("\n", strings.Count(comment, "\n"))
+	})
 
 	// Remove single-line comments (//)
 	singleLineComment := regexp.MustCompile(`//.*`)
@@ -55,26 +68,30 @@ func (ba *BaseSecurityAnalyzer) removeComments(code string) string {
 }
 
 // AnalyzeWithLLM performs LLM-based security analysis
-func (ba *BaseSecurityAnalyzer) AnalyzeWithLLM(code string, filePath string, securityRulesPrompt string) (*models.AnalysisResult, error) {
+func (ba *BaseSecurityAnalyzer) AnalyzeWithLLM(ctx context.Context, code string, filePath string, securityRulesPrompt string) (*models.AnalysisResult, error) {
 	startTime := time.Now()
 
 	// Preprocess code
 	preprocessed := ba.PreprocessCode(code, ba.Language)
+	ReportStage(ctx, StagePreprocess, "Code preprocessed, waiting for LLM analysis")
 
 	// Perform LLM analysis
-	response, err := ba.LLMService.Analyze(preprocessed, securityRulesPrompt)
+	response, provider, err := ba.LLMService.Analyze(ctx, preprocessed, securityRulesPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("LLM analysis failed: %w", err)
 	}
+	ReportStage(ctx, StageLLM, "LLM analysis complete")
 
 	// Parse LLM response
-	issues, err := ba.parseIssuesFromResponse(response, filePath)
+	issues, err := ba.parseIssuesFromResponse(ctx, response, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
 	}
+	ReportStage(ctx, StageParse, fmt.Sprintf("Parsed %d issue(s)", len(issues)))
 
 	// Generate metadata
 	metadata := ba.generateMetadata(issues, ba.Language, time.Since(startTime))
+	metadata.Provider = provider
 
 	// Generate summary
 	summary := ba.generateSummary(issues)
@@ -88,22 +105,30 @@ func (ba *BaseSecurityAnalyzer) AnalyzeWithLLM(code string, filePath string, sec
 }
 
 // parseIssuesFromResponse parses SecurityIssue objects from LLM response
-func (ba *BaseSecurityAnalyzer) parseIssuesFromResponse(response string, filePath string) ([]models.SecurityIssue, error) {
+func (ba *BaseSecurityAnalyzer) parseIssuesFromResponse(ctx context.Context, response string, filePath string) ([]models.SecurityIssue, error) {
 	// Extract JSON from response (it might be wrapped in markdown code blocks)
 	jsonStr := ba.extractJSON(response)
 
 	var issues []models.SecurityIssue
 	if err := json.Unmarshal([]byte(jsonStr), &issues); err != nil {
+		services.Log(ctx, services.LOG_DEBUG, "analyzer", "LLM response is not a JSON array (%v), trying an object with an issues field", err)
+
 		// If direct parsing fails, try wrapping in an object
 		var wrapper struct {
 			Issues []models.SecurityIssue `json:"issues"`
 		}
 		if err2 := json.Unmarshal([]byte(jsonStr), &wrapper); err2 != nil {
+			services.Log(ctx, services.LOG_WARNING, "analyzer", "Could not parse issues from LLM response: %v. Response starts with: %s", err, truncate(response, 200))
 			return nil, fmt.Errorf("failed to parse issues: %w", err)
 		}
 		issues = wrapper.Issues
 	}
 
+	// Keep empty lists as [] rather than null in JSON output
+	if issues == nil {
+		issues = []models.SecurityIssue{}
+	}
+
 	// Enrich issues with file path
 	for i := range issues {
 		if issues[i].FilePath == "" {
@@ -112,11 +137,32 @@ func (ba *BaseSecurityAnalyzer) parseIssuesFromResponse(response string, filePat
 		if issues[i].ID == "" {
 			issues[i].ID = fmt.Sprintf("ISSUE-%d", i+1)
 		}
+		if issues[i].References == nil {
+			issues[i].References = []string{}
+		}
+		// Drop rule IDs the LLM made up, and fill in the ones it left out
+		rule := MatchRule(ba.Rules, issues[i])
+		issues[i].RuleID = ""
+		if rule != nil {
+			issues[i].RuleID = rule.ID
+			if issues[i].Severity == "" {
+				issues[i].Severity = rule.Severity
+			}
+		}
+		issues[i].Fingerprint = Fingerprint(issues[i])
 	}
 
 	return issues, nil
 }
 
+// truncate shortens text to at most n bytes for log output
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[:n] + "..."
+}
+
 // extractJSON extracts JSON content from markdown code blocks or plain text
 func (ba *BaseSecurityAnalyzer) extractJSON(response string) string {
 	// Try to extract from markdown code block
@@ -192,6 +238,20 @@ func (ba *BaseSecurityAnalyzer) generateSummary(issues []models.SecurityIssue) s
 		len(issues), critical, high, medium, low)
 }
 
+// Recount refreshes the severity counts and summary of a result whose issues
+// were filtered or combined after analysis
+func Recount(result *models.AnalysisResult) {
+	ba := &BaseSecurityAnalyzer{Language: result.Language}
+
+	metadata := ba.generateMetadata(result.Issues, result.Language, 0)
+	metadata.AnalysisTime = result.AnalysisMetadata.AnalysisTime
+	metadata.Provider = result.AnalysisMetadata.Provider
+	metadata.Errors = result.AnalysisMetadata.Errors
+
+	result.AnalysisMetadata = metadata
+	result.Summary = ba.generateSummary(result.Issues)
+}
+
 // FormatAsMarkdown formats the analysis result as markdown
 func (ba *BaseSecurityAnalyzer) FormatAsMarkdown(result *models.AnalysisResult) string {
 	var sb strings.Builder
@@ -199,6 +259,9 @@ func (ba *BaseSecurityAnalyzer) FormatAsMarkdown(result *models.AnalysisResult)
 	sb.WriteString("# Security Analysis Report\n\n")
 	sb.WriteString(fmt.Sprintf("**Language**: %s\n\n", result.Language))
 	sb.WriteString(fmt.Sprintf("**Analysis Time**: %s\n\n", result.AnalysisMetadata.AnalysisTime))
+	if result.AnalysisMetadata.Provider != "" {
+		sb.WriteString(fmt.Sprintf("**Provider**: %s\n\n", result.AnalysisMetadata.Provider))
+	}
 	sb.WriteString(fmt.Sprintf("## Summary\n\n%s\n\n", result.Summary))
 
 	if len(result.Issues) == 0 {
@@ -289,5 +352,9 @@ func (ba *BaseSecurityAnalyzer) writeIssue(sb *strings.Builder, issue models.Sec
 		sb.WriteString("\n")
 	}
 
+	if issue.Fingerprint != "" {
+		sb.WriteString(fmt.Sprintf("**Fingerprint**: %s\n\n", issue.Fingerprint))
+	}
+
 	sb.WriteString("---\n\n")
 }
@@ -0,0 +1,90 @@
+package analyzers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestParseIssuesFromResponse(t *testing.T) {
+	analyzer := NewBaseAnalyzer(models.JAVA, nil)
+
+	tests := []struct {
+		name     string
+		response string
+		expected int
+	}{
+		{"Plain array", `[{"title":"SQL Injection","severity":"HIGH","line_number":3}]`, 1},
+		{"Markdown code block", "Here are the findings:\n```json\n[{\"title\":\"XXE\",\"severity\":\"CRITICAL\"}]\n```", 1},
+		{"Wrapped object", `{"issues":[{"title":"A"},{"title":"B"}]}`, 2},
+		{"Empty array", `[]`, 0},
+		{"Wrapper without issues", `{"summary":"clean"}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := analyzer.parseIssuesFromResponse(context.Background(), tt.response, "Example.java")
+			if err != nil {
+				t.Fatalf("parseIssuesFromResponse() error = %v", err)
+			}
+			if issues == nil {
+				t.Fatal("expected an empty slice, got nil")
+			}
+			if len(issues) != tt.expected {
+				t.Fatalf("expected %d issues, got %d", tt.expected, len(issues))
+			}
+
+			for i, issue := range issues {
+				if issue.FilePath != "Example.java" {
+					t.Errorf("issue %d file path = %q, want Example.java", i, issue.FilePath)
+				}
+				if issue.ID == "" || issue.References == nil {
+					t.Errorf("issue %d was not enriched: %+v", i, issue)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIssuesFromResponseInvalid(t *testing.T) {
+	analyzer := NewBaseAnalyzer(models.JAVA, nil)
+
+	if _, err := analyzer.parseIssuesFromResponse(context.Background(), "I could not analyze this code.", "Example.java"); err == nil {
+		t.Error("expected an error for a response without JSON")
+	}
+}
+
+func TestRecount(t *testing.T) {
+	result := &models.AnalysisResult{
+		Language: models.JAVA,
+		Issues: []models.SecurityIssue{
+			{Severity: models.CRITICAL},
+			{Severity: models.LOW},
+		},
+		AnalysisMetadata: models.AnalysisMetadata{AnalysisTime: "2s", Provider: "lmstudio", IssuesFound: 5, HighCount: 5},
+	}
+
+	Recount(result)
+
+	metadata := result.AnalysisMetadata
+	if metadata.IssuesFound != 2 || metadata.CriticalCount != 1 || metadata.LowCount != 1 || metadata.HighCount != 0 {
+		t.Errorf("unexpected counts: %+v", metadata)
+	}
+	if metadata.AnalysisTime != "2s" || metadata.Provider != "lmstudio" {
+		t.Errorf("expected timing and provider to be kept: %+v", metadata)
+	}
+	if result.Summary != "Found 2 security issue(s): 1 critical, 0 high, 0 medium, 1 low." {
+		t.Errorf("unexpected summary: %s", result.Summary)
+	}
+}
+
+func TestPreprocessCodeKeepsLines(t *testing.T) {
+	analyzer := NewBaseAnalyzer(models.JAVA, nil)
+
+	code := "class A {\n  /**\n   * Docs\n   */\n  void f() {} // trailing\n  /* inline */ int x;\n}"
+	expected := "class A {\n  \n\n\n  void f() {} \n   int x;\n}"
+	if preprocessed := analyzer.PreprocessCode(code, models.JAVA); preprocessed != expected {
+		t.Errorf("PreprocessCode() = %q, want %q", preprocessed, expected)
+	}
+}
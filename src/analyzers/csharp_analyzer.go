@@ -1,6 +1,8 @@
 package analyzers
 
 import (
+	"context"
+
 	"github.com/emware/aeyewire-mcp/src/models"
 	"github.com/emware/aeyewire-mcp/src/services"
 )
@@ -12,76 +14,78 @@ type CSharpAnalyzer struct {
 
 // NewCSharpAnalyzer creates a new C# security analyzer
 func NewCSharpAnalyzer(llmService *services.LLMService) *CSharpAnalyzer {
+	base := NewBaseAnalyzer(models.CSHARP, llmService)
+	base.Rules = csharpRules
 	return &CSharpAnalyzer{
-		BaseSecurityAnalyzer: NewBaseAnalyzer(models.CSHARP, llmService),
+		BaseSecurityAnalyzer: base,
 	}
 }
 
 // Analyze performs security analysis on C# code
-func (ca *CSharpAnalyzer) Analyze(code string, filePath string) (*models.AnalysisResult, error) {
+func (ca *CSharpAnalyzer) Analyze(ctx context.Context, code string, filePath string) (*models.AnalysisResult, error) {
 	prompt := ca.GetSecurityRulesPrompt()
-	return ca.AnalyzeWithLLM(code, filePath, prompt)
+	return ca.AnalyzeWithLLM(ctx, code, filePath, prompt)
 }
 
 // GetSecurityRulesPrompt returns the security rules prompt for C#
 func (ca *CSharpAnalyzer) GetSecurityRulesPrompt() string {
-	return `Analyze the following C# code for security vulnerabilities. Check for these 20+ security issues:
-
-INJECTION VULNERABILITIES:
-1. SQL Injection - String concatenation in SQL queries, missing parameterized queries
-2. Command Injection - Process.Start() or similar with unsanitized input
-3. LDAP Injection - String concatenation in LDAP queries
-4. XML Injection - Unsafe XML parsing allowing external entities
-
-CRYPTOGRAPHIC ISSUES:
-5. Weak Cryptography - DES, MD5, SHA1, hardcoded encryption keys
-6. Insecure Random Number Generation - Random class for security purposes
-7. Weak Password Hashing - Plain text or weak hashing algorithms
-
-DESERIALIZATION:
-8. Insecure Deserialization - BinaryFormatter, NetDataContractSerializer without validation
-
-AUTHENTICATION & AUTHORIZATION:
-9. Hardcoded Secrets - Passwords, API keys, connection strings in code
-10. Authentication Bypass - Missing authorization checks, weak password policies
-11. Session Management - Insecure session handling, missing timeout
-
-PATH TRAVERSAL & FILE HANDLING:
-12. Path Traversal - User input in file paths without validation
-13. Insecure File Operations - Unrestricted file upload, missing validation
-
-INPUT VALIDATION:
-14. Input Validation Issues - Missing validation, regex DoS
-15. Cross-Site Scripting (XSS) - Unencoded output in web applications
-
-CODE SECURITY:
-16. Code Injection - Dynamic code execution with user input (eval-like patterns)
-17. Unsafe Reflection - Type.GetType() or Assembly.Load() with user input
-
-CONFIGURATION & DEPLOYMENT:
-18. Debug Mode in Production - Debug flags enabled
-19. Information Disclosure - Detailed error messages, stack traces
-20. Insecure Direct Object References - Missing access control checks
-
-ADDITIONAL CONCERNS:
-21. CSRF Protection - Missing anti-forgery tokens
-22. Insecure Cookie Configuration - Missing HttpOnly, Secure flags
-23. Open Redirect - Redirect with unvalidated user input
-
-Return findings as a JSON array of security issues with this structure:
-[
-  {
-    "id": "unique-id",
-    "title": "Issue title",
-    "description": "Detailed description",
-    "severity": "CRITICAL|HIGH|MEDIUM|LOW",
-    "line_number": 0,
-    "column_number": 0,
-    "code_snippet": "vulnerable code",
-    "remediation": "How to fix",
-    "references": ["OWASP reference", "CWE-XXX"]
-  }
-]
+	return rulesPrompt("C#", ca.Rules, `"OWASP reference", "CWE-XXX"`)
+}
 
-Focus on actual vulnerabilities with specific line numbers and code snippets. If no issues are found, return an empty array [].`
+// csharpRules are the checks of the C# analyzer
+var csharpRules = []models.SecurityRule{
+	{ID: "csharp/sql-injection", Title: "SQL Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-89"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "String concatenation in SQL queries, missing parameterized queries"},
+	{ID: "csharp/command-injection", Title: "Command Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-78"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "Process.Start() or similar with unsanitized input"},
+	{ID: "csharp/ldap-injection", Title: "LDAP Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-90"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "String concatenation in LDAP queries"},
+	{ID: "csharp/xml-injection", Title: "XML Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-611"}, OWASP: OWASP_MISCONFIGURATION, Severity: models.HIGH,
+		Prompt: "Unsafe XML parsing allowing external entities"},
+
+	{ID: "csharp/weak-crypto", Title: "Weak Cryptography", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-327", "CWE-321"}, OWASP: OWASP_CRYPTO, Severity: models.HIGH,
+		Prompt: "DES, MD5, SHA1, hardcoded encryption keys"},
+	{ID: "csharp/insecure-random", Title: "Insecure Random Number Generation", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-330"}, OWASP: OWASP_CRYPTO, Severity: models.MEDIUM,
+		Prompt: "Random class for security purposes"},
+	{ID: "csharp/weak-password-hashing", Title: "Weak Password Hashing", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-916"}, OWASP: OWASP_CRYPTO, Severity: models.HIGH,
+		Prompt: "Plain text or weak hashing algorithms"},
+
+	{ID: "csharp/insecure-deserialization", Title: "Insecure Deserialization", Category: "DESERIALIZATION", CWE: []string{"CWE-502"}, OWASP: OWASP_INTEGRITY, Severity: models.CRITICAL,
+		Prompt: "BinaryFormatter, NetDataContractSerializer without validation"},
+
+	{ID: "csharp/hardcoded-secrets", Title: "Hardcoded Secrets", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-798"}, OWASP: OWASP_AUTHENTICATION, Severity: models.HIGH,
+		Prompt: "Passwords, API keys, connection strings in code"},
+	{ID: "csharp/authentication-bypass", Title: "Authentication Bypass", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-287", "CWE-862"}, OWASP: OWASP_AUTHENTICATION, Severity: models.CRITICAL,
+		Prompt: "Missing authorization checks, weak password policies"},
+	{ID: "csharp/session-management", Title: "Session Management", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-613"}, OWASP: OWASP_AUTHENTICATION, Severity: models.MEDIUM,
+		Prompt: "Insecure session handling, missing timeout"},
+
+	{ID: "csharp/path-traversal", Title: "Path Traversal", Category: "PATH TRAVERSAL & FILE HANDLING", CWE: []string{"CWE-22"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.HIGH,
+		Prompt: "User input in file paths without validation"},
+	{ID: "csharp/file-operations", Title: "Insecure File Operations", Category: "PATH TRAVERSAL & FILE HANDLING", CWE: []string{"CWE-434"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.HIGH,
+		Prompt: "Unrestricted file upload, missing validation"},
+
+	{ID: "csharp/input-validation", Title: "Input Validation Issues", Category: "INPUT VALIDATION", CWE: []string{"CWE-20", "CWE-1333"}, OWASP: OWASP_INJECTION, Severity: models.MEDIUM,
+		Prompt: "Missing validation, regex DoS"},
+	{ID: "csharp/xss", Title: "Cross-Site Scripting (XSS)", Category: "INPUT VALIDATION", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "Unencoded output in web applications"},
+
+	{ID: "csharp/code-injection", Title: "Code Injection", Category: "CODE SECURITY", CWE: []string{"CWE-94"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "Dynamic code execution with user input (eval-like patterns)"},
+	{ID: "csharp/unsafe-reflection", Title: "Unsafe Reflection", Category: "CODE SECURITY", CWE: []string{"CWE-470"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "Type.GetType() or Assembly.Load() with user input"},
+
+	{ID: "csharp/debug-mode", Title: "Debug Mode in Production", Category: "CONFIGURATION & DEPLOYMENT", CWE: []string{"CWE-489"}, OWASP: OWASP_MISCONFIGURATION, Severity: models.MEDIUM,
+		Prompt: "Debug flags enabled"},
+	{ID: "csharp/information-disclosure", Title: "Information Disclosure", Category: "CONFIGURATION & DEPLOYMENT", CWE: []string{"CWE-209"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Detailed error messages, stack traces"},
+	{ID: "csharp/idor", Title: "Insecure Direct Object References", Category: "CONFIGURATION & DEPLOYMENT", CWE: []string{"CWE-639"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.HIGH,
+		Prompt: "Missing access control checks"},
+
+	{ID: "csharp/csrf", Title: "CSRF Protection", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-352"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.MEDIUM,
+		Prompt: "Missing anti-forgery tokens"},
+	{ID: "csharp/insecure-cookie", Title: "Insecure Cookie Configuration", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-1004", "CWE-614"}, OWASP: OWASP_MISCONFIGURATION, Severity: models.LOW,
+		Prompt: "Missing HttpOnly, Secure flags"},
+	{ID: "csharp/open-redirect", Title: "Open Redirect", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-601"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.MEDIUM,
+		Prompt: "Redirect with unvalidated user input"},
 }
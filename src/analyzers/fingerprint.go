@@ -0,0 +1,54 @@
+package analyzers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// Fingerprint identifies an issue across analyses, unlike its positional
+// ID. It hashes the file path, the rule key and the flagged code, so it
+// survives edits elsewhere in the file that shift line numbers. The line
+// number is only used when the LLM quoted no code.
+func Fingerprint(issue models.SecurityIssue) string {
+	location := normalize(issue.CodeSnippet)
+	if location == "" {
+		location = fmt.Sprintf("line %d", issue.LineNumber)
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		filepath.ToSlash(issue.FilePath),
+		RuleKey(issue),
+		location,
+	}, "\x00")))
+	return hex.EncodeToString(hash[:8])
+}
+
+// RuleKey identifies the kind of weakness an issue reports, so findings of
+// two analyses can be matched regardless of how the LLM worded or numbered
+// them. It is the rule ID when the issue matched a rule, else its title.
+func RuleKey(issue models.SecurityIssue) string {
+	if issue.RuleID != "" {
+		return issue.RuleID
+	}
+	return normalize(issue.Title)
+}
+
+// SameRule reports whether two issues report the same kind of weakness. It
+// falls back to comparing titles when either issue matched no rule.
+func SameRule(a models.SecurityIssue, b models.SecurityIssue) bool {
+	if a.RuleID != "" && b.RuleID != "" {
+		return a.RuleID == b.RuleID
+	}
+	return normalize(a.Title) == normalize(b.Title)
+}
+
+// normalize lowercases text and collapses its whitespace, so formatting
+// differences between LLM responses do not change a fingerprint
+func normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,44 @@
+package analyzers
+
+import (
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestFingerprint(t *testing.T) {
+	issue := models.SecurityIssue{
+		ID:          "ISSUE-1",
+		Title:       "SQL Injection",
+		FilePath:    "src/Repo.java",
+		LineNumber:  12,
+		CodeSnippet: `String q = "SELECT " + id;`,
+	}
+	fingerprint := Fingerprint(issue)
+	if len(fingerprint) != 16 {
+		t.Fatalf("unexpected fingerprint %q", fingerprint)
+	}
+
+	moved := issue
+	moved.ID = "ISSUE-4"
+	moved.LineNumber = 40
+	moved.Title = "sql  injection"
+	moved.CodeSnippet = "  String q = \"SELECT \" +   id;\n"
+	if Fingerprint(moved) != fingerprint {
+		t.Error("expected the fingerprint to ignore the ID, line number and formatting")
+	}
+
+	other := issue
+	other.FilePath = "src/Other.java"
+	if Fingerprint(other) == fingerprint {
+		t.Error("expected the fingerprint to depend on the file")
+	}
+
+	unquoted := issue
+	unquoted.CodeSnippet = ""
+	shifted := unquoted
+	shifted.LineNumber = 13
+	if Fingerprint(unquoted) == Fingerprint(shifted) {
+		t.Error("expected the line number to distinguish issues without a snippet")
+	}
+}
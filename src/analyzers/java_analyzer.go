@@ -1,6 +1,8 @@
 package analyzers
 
 import (
+	"context"
+
 	"github.com/emware/aeyewire-mcp/src/models"
 	"github.com/emware/aeyewire-mcp/src/services"
 )
@@ -12,78 +14,82 @@ type JavaAnalyzer struct {
 
 // NewJavaAnalyzer creates a new Java security analyzer
 func NewJavaAnalyzer(llmService *services.LLMService) *JavaAnalyzer {
+	base := NewBaseAnalyzer(models.JAVA, llmService)
+	base.Rules = javaRules
 	return &JavaAnalyzer{
-		BaseSecurityAnalyzer: NewBaseAnalyzer(models.JAVA, llmService),
+		BaseSecurityAnalyzer: base,
 	}
 }
 
 // Analyze performs security analysis on Java code
-func (ja *JavaAnalyzer) Analyze(code string, filePath string) (*models.AnalysisResult, error) {
+func (ja *JavaAnalyzer) Analyze(ctx context.Context, code string, filePath string) (*models.AnalysisResult, error) {
 	prompt := ja.GetSecurityRulesPrompt()
-	return ja.AnalyzeWithLLM(code, filePath, prompt)
+	return ja.AnalyzeWithLLM(ctx, code, filePath, prompt)
 }
 
 // GetSecurityRulesPrompt returns the security rules prompt for Java
 func (ja *JavaAnalyzer) GetSecurityRulesPrompt() string {
-	return `Analyze the following Java code for security vulnerabilities. Check for these 25+ security issues:
-
-INJECTION VULNERABILITIES:
-1. SQL Injection - String concatenation in SQL queries, missing PreparedStatement
-2. Command Injection - Runtime.exec() or ProcessBuilder with unsanitized input
-3. LDAP Injection - String concatenation in LDAP filters
-4. XXE (XML External Entity) - DocumentBuilderFactory without disabled external entities
-5. JNDI Injection - Context.lookup() with user-controlled strings
-
-CRYPTOGRAPHIC ISSUES:
-6. Weak Cryptography - DES, 3DES, RC4, MD5, SHA1, ECB mode, hardcoded keys
-7. Insecure Random Number Generation - java.util.Random or Math.random() for security
-8. Insecure SSL/TLS Configuration - Trusting all certificates, disabled hostname verification
-
-DESERIALIZATION:
-9. Insecure Deserialization - ObjectInputStream.readObject() on untrusted data
-
-AUTHENTICATION & SESSION:
-10. Hardcoded Credentials - Passwords, API keys, secrets in code
-11. Session Management Flaws - Session IDs in URLs, missing timeout, no regeneration
-12. Authentication Bypass - Missing authentication checks, weak password policies
-
-PATH TRAVERSAL & FILE HANDLING:
-13. Path Traversal - User input in file paths without validation, ../ sequences
-14. Insecure File Upload - No file type validation, missing size limits
-15. Resource Leaks - Missing try-with-resources, unclosed connections
-
-CODE EXECUTION & REFLECTION:
-16. Unsafe Reflection - Class.forName() or Method.invoke() with user input
-17. Expression Language Injection - Unvalidated input in JSP/JSF/Spring EL, OGNL, SpEL
-
-SERVER-SIDE ATTACKS:
-18. SSRF (Server-Side Request Forgery) - URL fetching with user-controlled destinations
-
-INPUT VALIDATION:
-19. Regex DoS (ReDoS) - Nested quantifiers causing catastrophic backtracking
-20. Log Injection - Unvalidated user input in log statements
-21. Mass Assignment - Direct binding to object properties without validation
-
-ADDITIONAL CONCERNS:
-22. Insecure XML Processing - Unlimited entity expansion, XML bombs
-23. Unvalidated Redirects - response.sendRedirect() with user input
-24. JNI Security Issues - Unchecked native method calls
-25. Race Conditions & Concurrency - Check-then-act on shared resources, unsynchronized access
-
-Return findings as a JSON array of security issues with this structure:
-[
-  {
-    "id": "unique-id",
-    "title": "Issue title",
-    "description": "Detailed description",
-    "severity": "CRITICAL|HIGH|MEDIUM|LOW",
-    "line_number": 0,
-    "column_number": 0,
-    "code_snippet": "vulnerable code",
-    "remediation": "How to fix",
-    "references": ["OWASP reference", "CWE-XXX"]
-  }
-]
+	return rulesPrompt("Java", ja.Rules, `"OWASP reference", "CWE-XXX"`)
+}
 
-Focus on actual vulnerabilities with specific line numbers and code snippets. If no issues are found, return an empty array [].`
+// javaRules are the checks of the Java analyzer
+var javaRules = []models.SecurityRule{
+	{ID: "java/sql-injection", Title: "SQL Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-89"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "String concatenation in SQL queries, missing PreparedStatement"},
+	{ID: "java/command-injection", Title: "Command Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-78"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "Runtime.exec() or ProcessBuilder with unsanitized input"},
+	{ID: "java/ldap-injection", Title: "LDAP Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-90"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "String concatenation in LDAP filters"},
+	{ID: "java/xxe", Title: "XXE (XML External Entity)", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-611"}, OWASP: OWASP_MISCONFIGURATION, Severity: models.HIGH,
+		Prompt: "DocumentBuilderFactory without disabled external entities"},
+	{ID: "java/jndi-injection", Title: "JNDI Injection", Category: "INJECTION VULNERABILITIES", CWE: []string{"CWE-74"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "Context.lookup() with user-controlled strings"},
+
+	{ID: "java/weak-crypto", Title: "Weak Cryptography", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-327", "CWE-321"}, OWASP: OWASP_CRYPTO, Severity: models.HIGH,
+		Prompt: "DES, 3DES, RC4, MD5, SHA1, ECB mode, hardcoded keys"},
+	{ID: "java/insecure-random", Title: "Insecure Random Number Generation", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-330"}, OWASP: OWASP_CRYPTO, Severity: models.MEDIUM,
+		Prompt: "java.util.Random or Math.random() for security"},
+	{ID: "java/insecure-tls", Title: "Insecure SSL/TLS Configuration", Category: "CRYPTOGRAPHIC ISSUES", CWE: []string{"CWE-295"}, OWASP: OWASP_CRYPTO, Severity: models.HIGH,
+		Prompt: "Trusting all certificates, disabled hostname verification"},
+
+	{ID: "java/insecure-deserialization", Title: "Insecure Deserialization", Category: "DESERIALIZATION", CWE: []string{"CWE-502"}, OWASP: OWASP_INTEGRITY, Severity: models.CRITICAL,
+		Prompt: "ObjectInputStream.readObject() on untrusted data"},
+
+	{ID: "java/hardcoded-credentials", Title: "Hardcoded Credentials", Category: "AUTHENTICATION & SESSION", CWE: []string{"CWE-798"}, OWASP: OWASP_AUTHENTICATION, Severity: models.HIGH,
+		Prompt: "Passwords, API keys, secrets in code"},
+	{ID: "java/session-management", Title: "Session Management Flaws", Category: "AUTHENTICATION & SESSION", CWE: []string{"CWE-384", "CWE-613"}, OWASP: OWASP_AUTHENTICATION, Severity: models.MEDIUM,
+		Prompt: "Session IDs in URLs, missing timeout, no regeneration"},
+	{ID: "java/authentication-bypass", Title: "Authentication Bypass", Category: "AUTHENTICATION & SESSION", CWE: []string{"CWE-287"}, OWASP: OWASP_AUTHENTICATION, Severity: models.CRITICAL,
+		Prompt: "Missing authentication checks, weak password policies"},
+
+	{ID: "java/path-traversal", Title: "Path Traversal", Category: "PATH TRAVERSAL & FILE HANDLING", CWE: []string{"CWE-22"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.HIGH,
+		Prompt: "User input in file paths without validation, ../ sequences"},
+	{ID: "java/file-upload", Title: "Insecure File Upload", Category: "PATH TRAVERSAL & FILE HANDLING", CWE: []string{"CWE-434"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.HIGH,
+		Prompt: "No file type validation, missing size limits"},
+	{ID: "java/resource-leak", Title: "Resource Leaks", Category: "PATH TRAVERSAL & FILE HANDLING", CWE: []string{"CWE-772"}, Severity: models.LOW,
+		Prompt: "Missing try-with-resources, unclosed connections"},
+
+	{ID: "java/unsafe-reflection", Title: "Unsafe Reflection", Category: "CODE EXECUTION & REFLECTION", CWE: []string{"CWE-470"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "Class.forName() or Method.invoke() with user input"},
+	{ID: "java/expression-injection", Title: "Expression Language Injection", Category: "CODE EXECUTION & REFLECTION", CWE: []string{"CWE-917"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "Unvalidated input in JSP/JSF/Spring EL, OGNL, SpEL"},
+
+	{ID: "java/ssrf", Title: "SSRF (Server-Side Request Forgery)", Category: "SERVER-SIDE ATTACKS", CWE: []string{"CWE-918"}, OWASP: OWASP_SSRF, Severity: models.HIGH,
+		Prompt: "URL fetching with user-controlled destinations"},
+
+	{ID: "java/redos", Title: "Regex DoS (ReDoS)", Category: "INPUT VALIDATION", CWE: []string{"CWE-1333"}, Severity: models.MEDIUM,
+		Prompt: "Nested quantifiers causing catastrophic backtracking"},
+	{ID: "java/log-injection", Title: "Log Injection", Category: "INPUT VALIDATION", CWE: []string{"CWE-117"}, OWASP: OWASP_LOGGING, Severity: models.LOW,
+		Prompt: "Unvalidated user input in log statements"},
+	{ID: "java/mass-assignment", Title: "Mass Assignment", Category: "INPUT VALIDATION", CWE: []string{"CWE-915"}, OWASP: OWASP_INTEGRITY, Severity: models.MEDIUM,
+		Prompt: "Direct binding to object properties without validation"},
+
+	{ID: "java/xml-bomb", Title: "Insecure XML Processing", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-776"}, OWASP: OWASP_MISCONFIGURATION, Severity: models.MEDIUM,
+		Prompt: "Unlimited entity expansion, XML bombs"},
+	{ID: "java/open-redirect", Title: "Unvalidated Redirects", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-601"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.MEDIUM,
+		Prompt: "response.sendRedirect() with user input"},
+	{ID: "java/jni", Title: "JNI Security Issues", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-111"}, Severity: models.LOW,
+		Prompt: "Unchecked native method calls"},
+	{ID: "java/race-condition", Title: "Race Conditions & Concurrency", Category: "ADDITIONAL CONCERNS", CWE: []string{"CWE-362", "CWE-367"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.MEDIUM,
+		Prompt: "Check-then-act on shared resources, unsynchronized access"},
 }
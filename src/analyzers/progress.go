@@ -0,0 +1,61 @@
+package analyzers
+
+import "context"
+
+// Analysis stages reported through ReportStage, in execution order
+const (
+	StageDetect = iota + 1
+	StagePreprocess
+	StageLLM
+	StageParse
+	StageFormat
+
+	// StageCount is the number of stages in a single-unit analysis
+	StageCount = StageFormat
+)
+
+// ProgressFunc receives progress updates for a running analysis. Progress
+// increases towards total; message describes the stage just completed.
+type ProgressFunc func(progress float64, total float64, message string)
+
+type progressKey struct{}
+
+// progressScope locates the current unit inside a multi-unit analysis
+type progressScope struct {
+	report ProgressFunc
+	unit   int
+	units  int
+}
+
+// WithProgress returns a context whose analysis stages are reported to fn
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, &progressScope{report: fn, units: 1})
+}
+
+// ForUnit scopes progress reporting to unit (zero based) out of units, so
+// multi-file or chunked scans report a single progress bar across all units
+func ForUnit(ctx context.Context, unit int, units int) context.Context {
+	scope, ok := ctx.Value(progressKey{}).(*progressScope)
+	if !ok || units <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressKey{}, &progressScope{
+		report: scope.report,
+		unit:   scope.unit*units + unit,
+		units:  scope.units * units,
+	})
+}
+
+// ReportStage reports that stage has completed for the current unit. It is a
+// no-op when ctx carries no progress reporter.
+func ReportStage(ctx context.Context, stage int, message string) {
+	scope, ok := ctx.Value(progressKey{}).(*progressScope)
+	if !ok {
+		return
+	}
+
+	progress := float64(scope.unit*StageCount + stage)
+	total := float64(scope.units * StageCount)
+	scope.report(progress, total, message)
+}
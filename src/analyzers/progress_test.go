@@ -0,0 +1,64 @@
+package analyzers
+
+import (
+	"context"
+	"testing"
+)
+
+type progressUpdate struct {
+	progress float64
+	total    float64
+}
+
+func TestReportStageWithoutReporter(t *testing.T) {
+	// Must not panic when no reporter is installed
+	ReportStage(context.Background(), StageLLM, "ignored")
+	ReportStage(ForUnit(context.Background(), 1, 3), StageLLM, "ignored")
+}
+
+func TestReportStage(t *testing.T) {
+	var updates []progressUpdate
+	ctx := WithProgress(context.Background(), func(progress float64, total float64, message string) {
+		updates = append(updates, progressUpdate{progress, total})
+	})
+
+	ReportStage(ctx, StageDetect, "detect")
+	ReportStage(ctx, StageFormat, "format")
+
+	expected := []progressUpdate{{1, StageCount}, {StageCount, StageCount}}
+	if len(updates) != len(expected) {
+		t.Fatalf("expected %d updates, got %d", len(expected), len(updates))
+	}
+	for i := range expected {
+		if updates[i] != expected[i] {
+			t.Errorf("update %d = %+v, want %+v", i, updates[i], expected[i])
+		}
+	}
+}
+
+func TestForUnit(t *testing.T) {
+	var last progressUpdate
+	ctx := WithProgress(context.Background(), func(progress float64, total float64, message string) {
+		last = progressUpdate{progress, total}
+	})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		stage    int
+		expected progressUpdate
+	}{
+		{"First unit", ForUnit(ctx, 0, 3), StageDetect, progressUpdate{1, 3 * StageCount}},
+		{"Last unit done", ForUnit(ctx, 2, 3), StageFormat, progressUpdate{3 * StageCount, 3 * StageCount}},
+		{"Nested unit", ForUnit(ForUnit(ctx, 1, 2), 1, 2), StageParse, progressUpdate{3*StageCount + StageParse, 4 * StageCount}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ReportStage(tt.ctx, tt.stage, tt.name)
+			if last != tt.expected {
+				t.Errorf("ReportStage() = %+v, want %+v", last, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package analyzers
 
 import (
+	"context"
+
 	"github.com/emware/aeyewire-mcp/src/models"
 	"github.com/emware/aeyewire-mcp/src/services"
 )
@@ -12,85 +14,89 @@ type ReactAnalyzer struct {
 
 // NewReactAnalyzer creates a new React security analyzer
 func NewReactAnalyzer(llmService *services.LLMService, language models.LanguageType) *ReactAnalyzer {
+	base := NewBaseAnalyzer(language, llmService)
+	base.Rules = reactRules
+	if language == models.REACT_TYPESCRIPT {
+		base.Rules = append(append([]models.SecurityRule{}, reactRules...), typescriptRules...)
+	}
 	return &ReactAnalyzer{
-		BaseSecurityAnalyzer: NewBaseAnalyzer(language, llmService),
+		BaseSecurityAnalyzer: base,
 	}
 }
 
 // Analyze performs security analysis on React code
-func (ra *ReactAnalyzer) Analyze(code string, filePath string) (*models.AnalysisResult, error) {
+func (ra *ReactAnalyzer) Analyze(ctx context.Context, code string, filePath string) (*models.AnalysisResult, error) {
 	prompt := ra.GetSecurityRulesPrompt()
-	return ra.AnalyzeWithLLM(code, filePath, prompt)
+	return ra.AnalyzeWithLLM(ctx, code, filePath, prompt)
 }
 
 // GetSecurityRulesPrompt returns the security rules prompt for React
 func (ra *ReactAnalyzer) GetSecurityRulesPrompt() string {
-	basePrompt := `Analyze the following React code for security vulnerabilities. Check for these security issues:
-
-XSS (CROSS-SITE SCRIPTING):
-1. Dangerous HTML Rendering - dangerouslySetInnerHTML without sanitization
-2. Unescaped User Input - Direct rendering of user input in JSX
-3. URL Injection - Unsafe href or src attributes with user input
-4. Unsafe Attribute Binding - User-controlled event handlers
-
-STATE & PROPS SECURITY:
-5. Insecure State Management - Sensitive data in client-side state
-6. Props Validation - Missing PropTypes or TypeScript types for security-critical props
-7. State Mutation - Direct state mutations bypassing security checks
-
-API & DATA HANDLING:
-8. Insecure API Calls - Hardcoded API keys, credentials in code
-9. CSRF Protection - Missing CSRF tokens in API requests
-10. API Endpoint Exposure - Sensitive endpoints or data exposed
-11. Insecure Data Storage - Sensitive data in localStorage/sessionStorage
-
-AUTHENTICATION & AUTHORIZATION:
-12. Client-Side Auth Logic - Authentication decisions made purely on client
-13. Token Storage - Insecure JWT or token storage
-14. Missing Authorization Checks - Routes/components without proper access control
-
-INPUT VALIDATION:
-15. Form Validation - Missing or client-only validation
-16. File Upload Security - Unrestricted file uploads
-17. Regex DoS - Vulnerable regular expressions
-
-CONFIGURATION:
-18. Debug Code - console.log with sensitive data, debug flags in production
-19. Error Handling - Detailed error messages exposing system information
-20. Insecure Dependencies - Known vulnerabilities in npm packages
-
-REACT-SPECIFIC:
-21. Unsafe Refs - Direct DOM manipulation bypassing React security
-22. Third-Party Components - Untrusted or unvalidated component usage
-23. Code Injection - eval(), Function constructor, or dynamic code execution`
-
-	if ra.Language == models.REACT_TYPESCRIPT {
-		basePrompt += `
-
-TYPESCRIPT-SPECIFIC:
-24. Type Safety Bypass - 'any' type for security-critical data
-25. Type Assertions - Unsafe type casting that bypasses security checks
-26. Missing Null Checks - Potential null/undefined without proper guards`
-	}
-
-	basePrompt += `
-
-Return findings as a JSON array of security issues with this structure:
-[
-  {
-    "id": "unique-id",
-    "title": "Issue title",
-    "description": "Detailed description",
-    "severity": "CRITICAL|HIGH|MEDIUM|LOW",
-    "line_number": 0,
-    "column_number": 0,
-    "code_snippet": "vulnerable code",
-    "remediation": "How to fix",
-    "references": ["OWASP reference", "React Security Best Practices"]
-  }
-]
+	return rulesPrompt("React", ra.Rules, `"OWASP reference", "React Security Best Practices"`)
+}
 
-Focus on actual vulnerabilities with specific line numbers and code snippets. If no issues are found, return an empty array [].`
+// reactRules are the checks of the React analyzer for both languages
+var reactRules = []models.SecurityRule{
+	{ID: "react/dangerous-html", Title: "Dangerous HTML Rendering", Category: "XSS (CROSS-SITE SCRIPTING)", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "dangerouslySetInnerHTML without sanitization"},
+	{ID: "react/unescaped-input", Title: "Unescaped User Input", Category: "XSS (CROSS-SITE SCRIPTING)", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.MEDIUM,
+		Prompt: "Direct rendering of user input in JSX"},
+	{ID: "react/url-injection", Title: "URL Injection", Category: "XSS (CROSS-SITE SCRIPTING)", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.HIGH,
+		Prompt: "Unsafe href or src attributes with user input"},
+	{ID: "react/unsafe-attribute", Title: "Unsafe Attribute Binding", Category: "XSS (CROSS-SITE SCRIPTING)", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.MEDIUM,
+		Prompt: "User-controlled event handlers"},
+
+	{ID: "react/sensitive-state", Title: "Insecure State Management", Category: "STATE & PROPS SECURITY", CWE: []string{"CWE-200"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.MEDIUM,
+		Prompt: "Sensitive data in client-side state"},
+	{ID: "react/props-validation", Title: "Props Validation", Category: "STATE & PROPS SECURITY", CWE: []string{"CWE-20"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Missing PropTypes or TypeScript types for security-critical props"},
+	{ID: "react/state-mutation", Title: "State Mutation", Category: "STATE & PROPS SECURITY", CWE: []string{"CWE-471"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Direct state mutations bypassing security checks"},
+
+	{ID: "react/insecure-api-calls", Title: "Insecure API Calls", Category: "API & DATA HANDLING", CWE: []string{"CWE-798"}, OWASP: OWASP_AUTHENTICATION, Severity: models.HIGH,
+		Prompt: "Hardcoded API keys, credentials in code"},
+	{ID: "react/csrf", Title: "CSRF Protection", Category: "API & DATA HANDLING", CWE: []string{"CWE-352"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.MEDIUM,
+		Prompt: "Missing CSRF tokens in API requests"},
+	{ID: "react/endpoint-exposure", Title: "API Endpoint Exposure", Category: "API & DATA HANDLING", CWE: []string{"CWE-200"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.MEDIUM,
+		Prompt: "Sensitive endpoints or data exposed"},
+	{ID: "react/insecure-storage", Title: "Insecure Data Storage", Category: "API & DATA HANDLING", CWE: []string{"CWE-922"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.MEDIUM,
+		Prompt: "Sensitive data in localStorage/sessionStorage"},
+
+	{ID: "react/client-side-auth", Title: "Client-Side Auth Logic", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-602"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.HIGH,
+		Prompt: "Authentication decisions made purely on client"},
+	{ID: "react/token-storage", Title: "Token Storage", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-922"}, OWASP: OWASP_AUTHENTICATION, Severity: models.MEDIUM,
+		Prompt: "Insecure JWT or token storage"},
+	{ID: "react/missing-authorization", Title: "Missing Authorization Checks", Category: "AUTHENTICATION & AUTHORIZATION", CWE: []string{"CWE-862"}, OWASP: OWASP_ACCESS_CONTROL, Severity: models.HIGH,
+		Prompt: "Routes/components without proper access control"},
+
+	{ID: "react/form-validation", Title: "Form Validation", Category: "INPUT VALIDATION", CWE: []string{"CWE-20"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Missing or client-only validation"},
+	{ID: "react/file-upload", Title: "File Upload Security", Category: "INPUT VALIDATION", CWE: []string{"CWE-434"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.MEDIUM,
+		Prompt: "Unrestricted file uploads"},
+	{ID: "react/redos", Title: "Regex DoS", Category: "INPUT VALIDATION", CWE: []string{"CWE-1333"}, Severity: models.MEDIUM,
+		Prompt: "Vulnerable regular expressions"},
+
+	{ID: "react/debug-code", Title: "Debug Code", Category: "CONFIGURATION", CWE: []string{"CWE-532", "CWE-489"}, OWASP: OWASP_LOGGING, Severity: models.LOW,
+		Prompt: "console.log with sensitive data, debug flags in production"},
+	{ID: "react/error-handling", Title: "Error Handling", Category: "CONFIGURATION", CWE: []string{"CWE-209"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Detailed error messages exposing system information"},
+	{ID: "react/vulnerable-dependencies", Title: "Insecure Dependencies", Category: "CONFIGURATION", CWE: []string{"CWE-1104"}, OWASP: OWASP_VULNERABLE_DEPS, Severity: models.MEDIUM,
+		Prompt: "Known vulnerabilities in npm packages"},
+
+	{ID: "react/unsafe-refs", Title: "Unsafe Refs", Category: "REACT-SPECIFIC", CWE: []string{"CWE-79"}, OWASP: OWASP_INJECTION, Severity: models.MEDIUM,
+		Prompt: "Direct DOM manipulation bypassing React security"},
+	{ID: "react/untrusted-components", Title: "Third-Party Components", Category: "REACT-SPECIFIC", CWE: []string{"CWE-829"}, OWASP: OWASP_INTEGRITY, Severity: models.MEDIUM,
+		Prompt: "Untrusted or unvalidated component usage"},
+	{ID: "react/code-injection", Title: "Code Injection", Category: "REACT-SPECIFIC", CWE: []string{"CWE-95"}, OWASP: OWASP_INJECTION, Severity: models.CRITICAL,
+		Prompt: "eval(), Function constructor, or dynamic code execution"},
+}
 
-	return basePrompt
+// typescriptRules are the additional checks for React TypeScript
+var typescriptRules = []models.SecurityRule{
+	{ID: "react/ts-type-safety-bypass", Title: "Type Safety Bypass", Category: "TYPESCRIPT-SPECIFIC", CWE: []string{"CWE-20"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "'any' type for security-critical data"},
+	{ID: "react/ts-type-assertion", Title: "Type Assertions", Category: "TYPESCRIPT-SPECIFIC", CWE: []string{"CWE-704"}, OWASP: OWASP_INSECURE_DESIGN, Severity: models.LOW,
+		Prompt: "Unsafe type casting that bypasses security checks"},
+	{ID: "react/ts-null-check", Title: "Missing Null Checks", Category: "TYPESCRIPT-SPECIFIC", CWE: []string{"CWE-476"}, Severity: models.LOW,
+		Prompt: "Potential null/undefined without proper guards"},
 }
@@ -0,0 +1,96 @@
+package analyzers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// OWASP Top 10 (2021) categories referenced by the rules
+const (
+	OWASP_ACCESS_CONTROL   = "A01:2021-Broken Access Control"
+	OWASP_CRYPTO           = "A02:2021-Cryptographic Failures"
+	OWASP_INJECTION        = "A03:2021-Injection"
+	OWASP_INSECURE_DESIGN  = "A04:2021-Insecure Design"
+	OWASP_MISCONFIGURATION = "A05:2021-Security Misconfiguration"
+	OWASP_VULNERABLE_DEPS  = "A06:2021-Vulnerable and Outdated Components"
+	OWASP_AUTHENTICATION   = "A07:2021-Identification and Authentication Failures"
+	OWASP_INTEGRITY        = "A08:2021-Software and Data Integrity Failures"
+	OWASP_LOGGING          = "A09:2021-Security Logging and Monitoring Failures"
+	OWASP_SSRF             = "A10:2021-Server-Side Request Forgery"
+)
+
+// FormatRules formats rules as the numbered checklist used in prompts,
+// grouped under their categories, with each rule's ID in brackets so the
+// LLM can cite it
+func FormatRules(rules []models.SecurityRule) string {
+	var sb strings.Builder
+
+	category := ""
+	for i, rule := range rules {
+		if i == 0 || rule.Category != category {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			category = rule.Category
+			sb.WriteString(category + ":\n")
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s [%s] - %s\n", i+1, rule.Title, rule.ID, rule.Prompt))
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// rulesPrompt builds an analyzer's security rules prompt from its rules.
+// references is the example references list shown in the output format.
+func rulesPrompt(subject string, rules []models.SecurityRule, references string) string {
+	return fmt.Sprintf(`Analyze the following %s code for security vulnerabilities. Check for these %d security issues:
+
+%s
+
+Return findings as a JSON array of security issues with this structure:
+[
+  {
+    "id": "unique-id",
+    "rule_id": "ID in brackets of the rule above that the issue violates",
+    "title": "Issue title",
+    "description": "Detailed description",
+    "severity": "CRITICAL|HIGH|MEDIUM|LOW",
+    "line_number": 0,
+    "column_number": 0,
+    "code_snippet": "vulnerable code",
+    "remediation": "How to fix",
+    "references": [%s]
+  }
+]
+
+Focus on actual vulnerabilities with specific line numbers and code snippets. If no issues are found, return an empty array [].`, subject, len(rules), FormatRules(rules), references)
+}
+
+// FindRule returns the rule with the given ID, or nil
+func FindRule(rules []models.SecurityRule, id string) *models.SecurityRule {
+	id = normalize(id)
+	for i := range rules {
+		if rules[i].ID == id {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// MatchRule returns the rule an issue violates: the one it cites by ID, or
+// else the one whose title it repeats. It returns nil when neither matches.
+func MatchRule(rules []models.SecurityRule, issue models.SecurityIssue) *models.SecurityRule {
+	if rule := FindRule(rules, issue.RuleID); rule != nil {
+		return rule
+	}
+
+	title := normalize(issue.Title)
+	for i := range rules {
+		if normalize(rules[i].Title) == title {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package analyzers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestRuleTables(t *testing.T) {
+	seen := map[string]bool{}
+	for _, rules := range [][]models.SecurityRule{javaRules, csharpRules, reactRules, typescriptRules} {
+		for _, rule := range rules {
+			if seen[rule.ID] {
+				t.Errorf("duplicate rule ID %s", rule.ID)
+			}
+			seen[rule.ID] = true
+
+			if rule.ID != strings.ToLower(rule.ID) || rule.Title == "" || rule.Category == "" || rule.Prompt == "" {
+				t.Errorf("incomplete rule %+v", rule)
+			}
+			if len(rule.CWE) == 0 || rule.Severity == "" {
+				t.Errorf("rule %s lacks a CWE ID or default severity", rule.ID)
+			}
+		}
+	}
+}
+
+func TestGetSecurityRulesPrompt(t *testing.T) {
+	prompt := NewJavaAnalyzer(nil).GetSecurityRulesPrompt()
+	for _, expected := range []string{
+		"Check for these 25 security issues:",
+		"INJECTION VULNERABILITIES:\n1. SQL Injection [java/sql-injection] - String concatenation in SQL queries",
+		"\n\nCRYPTOGRAPHIC ISSUES:\n6. Weak Cryptography [java/weak-crypto]",
+		`"rule_id":`,
+	} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("expected the prompt to contain %q", expected)
+		}
+	}
+
+	javascript := NewReactAnalyzer(nil, models.REACT_JAVASCRIPT).GetSecurityRulesPrompt()
+	typescript := NewReactAnalyzer(nil, models.REACT_TYPESCRIPT).GetSecurityRulesPrompt()
+	if strings.Contains(javascript, "TYPESCRIPT-SPECIFIC") || !strings.Contains(typescript, "TYPESCRIPT-SPECIFIC:\n24. Type Safety Bypass") {
+		t.Error("expected TypeScript rules in the React TypeScript prompt only")
+	}
+}
+
+func TestParseIssuesRuleID(t *testing.T) {
+	analyzer := NewJavaAnalyzer(nil)
+
+	response := `[
+		{"rule_id":"JAVA/SQL-INJECTION","title":"Query built from input","severity":"HIGH"},
+		{"title":"Weak cryptography"},
+		{"rule_id":"java/made-up","title":"Something else","severity":"LOW"}
+	]`
+	issues, err := analyzer.parseIssuesFromResponse(context.Background(), response, "Example.java")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"java/sql-injection", "java/weak-crypto", ""}
+	for i, issue := range issues {
+		if issue.RuleID != expected[i] {
+			t.Errorf("issue %d rule ID = %q, want %q", i, issue.RuleID, expected[i])
+		}
+	}
+	if issues[1].Severity != models.HIGH {
+		t.Errorf("expected the rule's default severity, got %q", issues[1].Severity)
+	}
+	if RuleKey(issues[0]) != "java/sql-injection" || RuleKey(issues[2]) != "something else" {
+		t.Errorf("unexpected rule keys %q and %q", RuleKey(issues[0]), RuleKey(issues[2]))
+	}
+}
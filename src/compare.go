@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// reportComparison classifies the findings of two analysis results
+type reportComparison struct {
+	// New are the findings of the second result missing from the first
+	New []models.SecurityIssue `json:"new"`
+	// Fixed are the findings of the first result missing from the second
+	Fixed []models.SecurityIssue `json:"fixed"`
+	// Unchanged are the findings of the second result also in the first
+	Unchanged []models.SecurityIssue `json:"unchanged"`
+}
+
+// compareReports matches the findings of two analysis results by
+// fingerprint. Fingerprints are recomputed rather than trusted, so reports
+// written before fingerprints existed compare the same way; a fingerprint
+// found several times on one side matches as many times on the other.
+func compareReports(before *models.AnalysisResult, after *models.AnalysisResult) *reportComparison {
+	comparison := &reportComparison{
+		New:       []models.SecurityIssue{},
+		Fixed:     []models.SecurityIssue{},
+		Unchanged: []models.SecurityIssue{},
+	}
+
+	remaining := map[string][]models.SecurityIssue{}
+	for _, issue := range before.Issues {
+		issue.Fingerprint = analyzers.Fingerprint(issue)
+		remaining[issue.Fingerprint] = append(remaining[issue.Fingerprint], issue)
+	}
+
+	for _, issue := range after.Issues {
+		issue.Fingerprint = analyzers.Fingerprint(issue)
+		if matches := remaining[issue.Fingerprint]; len(matches) > 0 {
+			remaining[issue.Fingerprint] = matches[1:]
+			comparison.Unchanged = append(comparison.Unchanged, issue)
+		} else {
+			comparison.New = append(comparison.New, issue)
+		}
+	}
+
+	// Walk the first result again to list fixed findings in report order
+	for _, issue := range before.Issues {
+		fingerprint := analyzers.Fingerprint(issue)
+		if matches := remaining[fingerprint]; len(matches) > 0 {
+			remaining[fingerprint] = matches[1:]
+			comparison.Fixed = append(comparison.Fixed, matches[0])
+		}
+	}
+
+	return comparison
+}
+
+// parseAnalysisResult decodes an AnalysisResult JSON document
+func parseAnalysisResult(data []byte) (*models.AnalysisResult, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if _, ok := document["issues"]; !ok {
+		return nil, fmt.Errorf("not an analysis result: missing issues")
+	}
+
+	var result models.AnalysisResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// handleCompareReports handles the compare_reports tool
+func (s *MCPServer) handleCompareReports(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	results := []*models.AnalysisResult{}
+	for _, name := range []string{"before", "after"} {
+		jsonData, _ := json.Marshal(args[name])
+		result, err := parseAnalysisResult(jsonData)
+		if err != nil {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid '%s' report: %v", name, err)}
+		}
+		results = append(results, result)
+	}
+
+	comparison := compareReports(results[0], results[1])
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatReportComparison(comparison),
+			},
+		},
+		"structuredContent": comparison,
+	}
+	return response, nil
+}
+
+// formatReportComparison formats a comparison as markdown
+func formatReportComparison(comparison *reportComparison) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Report Comparison\n\n")
+	sb.WriteString(fmt.Sprintf("%d new, %d fixed, %d unchanged finding(s).\n\n",
+		len(comparison.New), len(comparison.Fixed), len(comparison.Unchanged)))
+
+	sections := []struct {
+		title  string
+		issues []models.SecurityIssue
+	}{
+		{"New", comparison.New},
+		{"Fixed", comparison.Fixed},
+		{"Unchanged", comparison.Unchanged},
+	}
+	for _, section := range sections {
+		if len(section.issues) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n", section.title))
+		writeIssueList(&sb, section.issues)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// reportCommand runs the report CLI command. Its only subcommand, diff,
+// compares two AnalysisResult JSON files without contacting the LLM and
+// exits with status 2 when the second has new findings.
+func reportCommand(args []string) {
+	if len(args) == 0 || args[0] != "diff" {
+		fmt.Println("Usage: aeyewire_mcp report diff [--json] <before.json> <after.json>")
+		os.Exit(1)
+	}
+
+	asJSON := false
+	paths := []string{}
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "--json":
+			asJSON = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Printf("Unknown option: %s\n", arg)
+			os.Exit(1)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) != 2 {
+		fmt.Println("Error: report diff takes two report files")
+		os.Exit(1)
+	}
+
+	results := []*models.AnalysisResult{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("Error reading report: %v\n", err)
+			os.Exit(1)
+		}
+		result, err := parseAnalysisResult(data)
+		if err != nil {
+			fmt.Printf("Error parsing %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		results = append(results, result)
+	}
+
+	comparison := compareReports(results[0], results[1])
+	if asJSON {
+		jsonData, _ := json.MarshalIndent(comparison, "", "  ")
+		fmt.Println(string(jsonData))
+	} else {
+		fmt.Print(formatReportComparison(comparison))
+	}
+	if len(comparison.New) > 0 {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestCompareReports(t *testing.T) {
+	sqli := models.SecurityIssue{ID: "ISSUE-1", Title: "SQL Injection", LineNumber: 3, FilePath: "A.java", CodeSnippet: "db.query(sql + id)"}
+	hash := models.SecurityIssue{ID: "ISSUE-2", Title: "Weak Hash", LineNumber: 9, FilePath: "A.java", CodeSnippet: "MD5"}
+	before := &models.AnalysisResult{Issues: []models.SecurityIssue{sqli, hash, hash}}
+
+	// The SQL injection moved and was renumbered, one weak hash was fixed
+	moved := sqli
+	moved.ID, moved.LineNumber = "ISSUE-2", 12
+	xss := models.SecurityIssue{ID: "ISSUE-1", Title: "XSS", LineNumber: 4, FilePath: "A.java", CodeSnippet: "out.print(name)"}
+	after := &models.AnalysisResult{Issues: []models.SecurityIssue{xss, moved, hash}}
+
+	comparison := compareReports(before, after)
+	if len(comparison.New) != 1 || comparison.New[0].Title != "XSS" {
+		t.Errorf("expected the XSS to be new, got %+v", comparison.New)
+	}
+	if len(comparison.Fixed) != 1 || comparison.Fixed[0].Title != "Weak Hash" {
+		t.Errorf("expected one weak hash to be fixed, got %+v", comparison.Fixed)
+	}
+	if len(comparison.Unchanged) != 2 || comparison.Unchanged[0].LineNumber != 12 {
+		t.Errorf("expected the moved SQL injection and a weak hash to be unchanged, got %+v", comparison.Unchanged)
+	}
+	for _, issue := range comparison.Unchanged {
+		if issue.Fingerprint == "" {
+			t.Errorf("expected fingerprints to be filled in, got %+v", issue)
+		}
+	}
+}
+
+func TestCompareReportsTool(t *testing.T) {
+	server := NewMCPServer()
+
+	report := func(titles ...string) map[string]interface{} {
+		issues := []interface{}{}
+		for i, title := range titles {
+			issues = append(issues, map[string]interface{}{"id": "ISSUE-1", "title": title, "severity": "HIGH", "line_number": float64(i + 1)})
+		}
+		return map[string]interface{}{"language": "java", "issues": issues}
+	}
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "compare_reports",
+		"arguments": map[string]interface{}{"before": report("SQL Injection"), "after": report("SQL Injection", "XSS")},
+	})
+	if response.Error != nil {
+		t.Fatalf("compare_reports failed: %s", response.Error.Message)
+	}
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		StructuredContent reportComparison `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+	if len(result.StructuredContent.New) != 1 || len(result.StructuredContent.Unchanged) != 1 || len(result.StructuredContent.Fixed) != 0 {
+		t.Errorf("unexpected comparison: %s", jsonData)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "compare_reports",
+		"arguments": map[string]interface{}{"before": map[string]interface{}{"files": []interface{}{}}, "after": report()},
+	})
+	if response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("expected a document without issues to be rejected, got %+v", response)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// MAX_COMPLETION_VALUES is the most values a completion/complete result may
+// carry under the MCP specification
+const MAX_COMPLETION_VALUES = 100
+
+// handleComplete handles completion/complete request. The language and
+// file_path arguments of the prompt templates and the language of the rule
+// catalog template are completed; other arguments get no suggestions.
+func (s *MCPServer) handleComplete(ctx context.Context, request *MCPRequest) (interface{}, *MCPError) {
+	ref, _ := request.Params["ref"].(map[string]interface{})
+	argument, _ := request.Params["argument"].(map[string]interface{})
+	refType, _ := ref["type"].(string)
+	argumentName, _ := argument["name"].(string)
+	value, _ := argument["value"].(string)
+
+	if argumentName == "" {
+		return nil, &MCPError{Code: -32602, Message: "Missing argument name"}
+	}
+
+	switch refType {
+	case "ref/prompt":
+		name, _ := ref["name"].(string)
+		if findPrompt(name) == nil {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unknown prompt: %s", name)}
+		}
+	case "ref/resource":
+		uri, _ := ref["uri"].(string)
+		if uri != RULES_URI_PREFIX+"{language}" {
+			return completionResult(nil, false), nil
+		}
+	default:
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid completion reference type: %q", refType)}
+	}
+
+	switch argumentName {
+	case "language":
+		return completionResult(s.completeLanguage(value), false), nil
+	case "file_path":
+		if refType != "ref/prompt" {
+			break
+		}
+		values, hasMore, err := s.completeFilePath(ctx, sessionFrom(ctx), value)
+		if err != nil {
+			return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Could not list workspace files: %v", err)}
+		}
+		return completionResult(values, hasMore), nil
+	}
+	return completionResult(nil, false), nil
+}
+
+// completionResult builds a completion/complete result
+func completionResult(values []string, hasMore bool) map[string]interface{} {
+	if values == nil {
+		values = []string{}
+	}
+
+	completion := map[string]interface{}{
+		"values":  values,
+		"hasMore": hasMore,
+	}
+	if !hasMore {
+		completion["total"] = len(values)
+	}
+	return map[string]interface{}{"completion": completion}
+}
+
+// completeLanguage returns the registered languages, and "auto", that start
+// with prefix
+func (s *MCPServer) completeLanguage(prefix string) []string {
+	values := []string{}
+	for _, language := range append(s.sortedLanguages(), models.LanguageType("auto")) {
+		if strings.HasPrefix(string(language), strings.ToLower(prefix)) {
+			values = append(values, string(language))
+		}
+	}
+	return values
+}
+
+// completeFilePath returns the source files under the workspace roots whose
+// path or file name starts with prefix and whose extension the language
+// detector recognizes. Files under the first root are offered relative to it,
+// as relative paths resolve against that root; others are offered absolute.
+func (s *MCPServer) completeFilePath(ctx context.Context, sess *session, prefix string) ([]string, bool, error) {
+	roots, err := s.workspaceRoots(ctx, sess)
+	if err != nil {
+		return nil, false, err
+	}
+
+	values := []string{}
+	hasMore := false
+	for i, root := range roots {
+		err := s.walkSourceFiles(ctx, root, func(path string) error {
+			candidate := path
+			if i == 0 {
+				candidate, _ = filepath.Rel(root, path)
+				candidate = filepath.ToSlash(candidate)
+			}
+			if !matchesPrefix(candidate, prefix) {
+				return nil
+			}
+
+			if len(values) == MAX_COMPLETION_VALUES {
+				hasMore = true
+				return filepath.SkipAll
+			}
+			values = append(values, candidate)
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		if hasMore {
+			break
+		}
+	}
+	return values, hasMore, nil
+}
+
+// matchesPrefix reports whether the path or its file name starts with prefix,
+// ignoring case, so "login" finds src/LoginController.java
+func matchesPrefix(path string, prefix string) bool {
+	prefix = strings.ToLower(prefix)
+	return strings.HasPrefix(strings.ToLower(path), prefix) ||
+		strings.HasPrefix(strings.ToLower(filepath.Base(path)), prefix)
+}
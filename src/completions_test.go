@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// completionValues calls completion/complete and returns the suggested values
+func completionValues(t *testing.T, server *MCPServer, ref map[string]interface{}, argument string, value string) []string {
+	t.Helper()
+
+	response := callMethod(t, server, "completion/complete", map[string]interface{}{
+		"ref":      ref,
+		"argument": map[string]interface{}{"name": argument, "value": value},
+	})
+	if response.Error != nil {
+		t.Fatalf("completion/complete failed: %s", response.Error.Message)
+	}
+	completion := response.Result.(map[string]interface{})["completion"].(map[string]interface{})
+	return completion["values"].([]string)
+}
+
+func TestCompleteLanguage(t *testing.T) {
+	server := NewMCPServer()
+	prompt := map[string]interface{}{"type": "ref/prompt", "name": "security_review"}
+
+	if values := completionValues(t, server, prompt, "language", "react"); !reflect.DeepEqual(values, []string{"react_javascript", "react_typescript"}) {
+		t.Errorf("unexpected completions for react: %v", values)
+	}
+	if values := completionValues(t, server, prompt, "language", ""); len(values) != len(server.analyzers)+1 {
+		t.Errorf("expected every language plus auto, got %v", values)
+	}
+
+	rules := map[string]interface{}{"type": "ref/resource", "uri": RULES_URI_PREFIX + "{language}"}
+	if values := completionValues(t, server, rules, "language", "j"); !reflect.DeepEqual(values, []string{"java"}) {
+		t.Errorf("unexpected rule catalog completions: %v", values)
+	}
+
+	response := callMethod(t, server, "completion/complete", map[string]interface{}{
+		"ref":      map[string]interface{}{"type": "ref/prompt", "name": "no_such_prompt"},
+		"argument": map[string]interface{}{"name": "language", "value": ""},
+	})
+	if response.Error == nil {
+		t.Error("expected an unknown prompt to be rejected")
+	}
+}
+
+func TestCompleteFilePath(t *testing.T) {
+	workspace := t.TempDir()
+	for _, file := range []string{
+		"src/LoginController.java",
+		"src/App.tsx",
+		"src/README.md",
+		"node_modules/lib/index.js",
+		".git/hooks/pre-commit.js",
+	} {
+		path := filepath.Join(workspace, file)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte{}, 0o644)
+	}
+	t.Chdir(workspace)
+
+	server := NewMCPServer()
+	prompt := map[string]interface{}{"type": "ref/prompt", "name": "security_review"}
+
+	if values := completionValues(t, server, prompt, "file_path", ""); !reflect.DeepEqual(values, []string{"src/App.tsx", "src/LoginController.java"}) {
+		t.Errorf("expected only recognized source files, got %v", values)
+	}
+	if values := completionValues(t, server, prompt, "file_path", "login"); !reflect.DeepEqual(values, []string{"src/LoginController.java"}) {
+		t.Errorf("expected a file name match, got %v", values)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// DIFF_CONTEXT_LINES is how many unchanged lines around each hunk are sent
+// to the analyzer, so the LLM sees where changed code gets its input from
+const DIFF_CONTEXT_LINES = 20
+
+// hunkHeader matches "@@ -oldStart[,oldLines] +newStart[,newLines] @@"
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// diffHunk is the new-file line range covered by one hunk
+type diffHunk struct {
+	NewStart int
+	NewLines int
+}
+
+// diffFile is the part of a unified diff that changes one file
+type diffFile struct {
+	// Path is the new path of the file, without the b/ prefix git adds
+	Path  string
+	Hunks []diffHunk
+	// Added maps the new-file line numbers of added or modified lines to
+	// their text
+	Added map[int]string
+}
+
+// parseUnifiedDiff returns the files changed by a unified diff, as produced
+// by git diff or diff -u. Deleted files are omitted, since no new line can
+// carry an issue.
+func parseUnifiedDiff(diff string) ([]*diffFile, error) {
+	files := []*diffFile{}
+	var current *diffFile
+	newLine, remaining := 0, 0
+
+	scanner := bufio.NewScanner(strings.NewReader(normalizeLineEndings(diff)))
+	scanner.Buffer(make([]byte, 0, 64*1024), MAX_SOURCE_FILE_SIZE)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if remaining > 0 && current != nil {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				current.Added[newLine] = line[1:]
+				newLine++
+				remaining--
+			case strings.HasPrefix(line, " "), line == "":
+				newLine++
+				remaining--
+			case strings.HasPrefix(line, "-"), strings.HasPrefix(line, `\`):
+			default:
+				return nil, fmt.Errorf("unexpected line in hunk of %s: %q", current.Path, truncateLine(line))
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			name := diffPath(line[4:])
+			if name == "" {
+				current = nil
+				continue
+			}
+			current = &diffFile{Path: name, Added: map[int]string{}}
+			files = append(files, current)
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				continue
+			}
+			match := hunkHeader.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("malformed hunk header in %s: %q", current.Path, truncateLine(line))
+			}
+			hunk := diffHunk{NewLines: 1}
+			hunk.NewStart, _ = strconv.Atoi(match[1])
+			if match[2] != "" {
+				hunk.NewLines, _ = strconv.Atoi(match[2])
+			}
+			current.Hunks = append(current.Hunks, hunk)
+			newLine, remaining = hunk.NewStart, hunk.NewLines
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Files whose hunks only delete lines have nothing to review
+	changed := []*diffFile{}
+	for _, file := range files {
+		if len(file.Added) > 0 {
+			changed = append(changed, file)
+		}
+	}
+	return changed, nil
+}
+
+// diffPath extracts the file path from the name on a "+++" line, returning ""
+// for /dev/null
+func diffPath(name string) string {
+	// diff -u appends a tab and the modification time
+	if i := strings.IndexByte(name, '\t'); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimSpace(name)
+	if name == "/dev/null" {
+		return ""
+	}
+	if unquoted, err := strconv.Unquote(name); err == nil {
+		name = unquoted
+	}
+	if strings.HasPrefix(name, "b/") {
+		name = name[2:]
+	}
+	return path.Clean(name)
+}
+
+// truncateLine shortens a diff line quoted in an error message
+func truncateLine(line string) string {
+	if len(line) > 80 {
+		return line[:80] + "..."
+	}
+	return line
+}
+
+// windows returns the new-file line ranges to analyze: each hunk widened by
+// DIFF_CONTEXT_LINES and clamped to lineCount, with overlaps merged
+func (file *diffFile) windows(lineCount int) [][2]int {
+	windows := [][2]int{}
+	for _, hunk := range file.Hunks {
+		start := max(hunk.NewStart-DIFF_CONTEXT_LINES, 1)
+		end := min(hunk.NewStart+hunk.NewLines-1+DIFF_CONTEXT_LINES, lineCount)
+		if start > end {
+			continue
+		}
+
+		if last := len(windows) - 1; last >= 0 && start <= windows[last][1]+1 {
+			windows[last][1] = max(windows[last][1], end)
+			continue
+		}
+		windows = append(windows, [2]int{start, end})
+	}
+	return windows
+}
+
+// analyzeDiffFile analyzes the changed regions of one file, given its
+// post-change contents, and keeps only the issues on added or modified
+// lines. Issue line numbers refer to the new file. The result is stored as
+// a report of the session, without a source file since the contents may
+// not be those on disk.
+func (s *MCPServer) analyzeDiffFile(ctx context.Context, languageStr string, file *diffFile, code string) scanFileResult {
+	lines := strings.Split(normalizeLineEndings(code), "\n")
+	for number, text := range file.Added {
+		if number > len(lines) || lines[number-1] != text {
+			return scanFileResult{FilePath: file.Path, Error: fmt.Sprintf("The diff does not match the contents of %s at line %d", file.Path, number)}
+		}
+	}
+
+	language, analyzer, mcpErr := s.resolveAnalyzer(ctx, languageStr, code, file.Path)
+	if mcpErr != nil {
+		return scanFileResult{FilePath: file.Path, Error: mcpErr.Message}
+	}
+
+	startTime := time.Now()
+	result := &models.AnalysisResult{Language: language, Issues: []models.SecurityIssue{}}
+	windows := file.windows(len(lines))
+	for i, window := range windows {
+		snippet := strings.Join(lines[window[0]-1:window[1]], "\n")
+		analysis, err := analyzer.Analyze(analyzers.ForUnit(ctx, i, len(windows)), snippet, file.Path)
+		if err != nil {
+			return scanFileResult{FilePath: file.Path, Error: fmt.Sprintf("Analysis failed: %v", err)}
+		}
+		result.AnalysisMetadata.Provider = analysis.AnalysisMetadata.Provider
+		result.AnalysisMetadata.Errors = append(result.AnalysisMetadata.Errors, analysis.AnalysisMetadata.Errors...)
+
+		for _, issue := range analysis.Issues {
+			// Line numbers are relative to the snippet; issues without one
+			// cannot be attributed to the change
+			if issue.LineNumber <= 0 || issue.LineNumber > window[1]-window[0]+1 {
+				continue
+			}
+			issue.LineNumber += window[0] - 1
+			if _, ok := file.Added[issue.LineNumber]; !ok {
+				continue
+			}
+			issue.ID = fmt.Sprintf("ISSUE-%d", len(result.Issues)+1)
+			issue.Fingerprint = analyzers.Fingerprint(issue)
+			result.Issues = append(result.Issues, issue)
+		}
+	}
+
+	result.AnalysisMetadata.AnalysisTime = time.Since(startTime).String()
+	analyzers.Recount(result)
+	s.storeResult(sessionFrom(ctx).id, "", file.Path, result)
+	return scanFileResult{FilePath: file.Path, Result: result}
+}
+
+// handleAnalyzeDiff handles the analyze_diff tool
+func (s *MCPServer) handleAnalyzeDiff(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	diff, _ := args["diff"].(string)
+	languageStr, _ := args["language"].(string)
+
+	contents := map[string]string{}
+	list, _ := args["files"].([]interface{})
+	for _, item := range list {
+		entry := item.(map[string]interface{})
+		name, _ := entry["path"].(string)
+		content, _ := entry["content"].(string)
+		contents[path.Clean(strings.ReplaceAll(name, "\\", "/"))] = content
+	}
+
+	changed, err := parseUnifiedDiff(diff)
+	if err != nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid diff: %v", err)}
+	}
+	if len(changed) == 0 {
+		return nil, &MCPError{Code: -32602, Message: "The diff adds or modifies no lines"}
+	}
+
+	files := []scanFileResult{}
+	failed := 0
+	for i, file := range changed {
+		code, ok := contents[file.Path]
+		if !ok {
+			failed++
+			files = append(files, scanFileResult{FilePath: file.Path, Error: fmt.Sprintf("No post-change contents supplied for %s", file.Path)})
+			continue
+		}
+
+		result := s.analyzeDiffFile(analyzers.ForUnit(ctx, i, len(changed)), languageStr, file, code)
+		if ctx.Err() != nil {
+			return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Analysis failed: %v", ctx.Err())}
+		}
+		if result.Result == nil {
+			failed++
+		}
+		files = append(files, result)
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatDiffReport(changed, files),
+			},
+		},
+		"structuredContent": map[string]interface{}{"files": files},
+	}
+	if failed == len(files) {
+		response["isError"] = true
+	}
+	return response, nil
+}
+
+// formatDiffReport formats the review of a diff as markdown, one section per
+// changed file
+func formatDiffReport(changed []*diffFile, files []scanFileResult) string {
+	var sb strings.Builder
+
+	issues := 0
+	for _, file := range files {
+		if file.Result != nil {
+			issues += len(file.Result.Issues)
+		}
+	}
+
+	sb.WriteString("# Diff Security Review\n\n")
+	sb.WriteString(fmt.Sprintf("**Files Changed**: %d\n", len(files)))
+	sb.WriteString(fmt.Sprintf("**Issues on Changed Lines**: %d\n\n", issues))
+
+	for i, file := range files {
+		sb.WriteString(fmt.Sprintf("## %s (%d line(s) added or modified)\n\n", file.FilePath, len(changed[i].Added)))
+		switch {
+		case file.Result == nil:
+			sb.WriteString(fmt.Sprintf("Error: %s\n\n", file.Error))
+		case len(file.Result.Issues) == 0:
+			sb.WriteString("No security issues found on changed lines.\n\n")
+		default:
+			writeIssueList(&sb, file.Result.Issues)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/src/Login.java b/src/Login.java
+index 3b18e51..a9c2d4f 100644
+--- a/src/Login.java
++++ b/src/Login.java
+@@ -2,3 +2,4 @@ class Login {
+ line 2
+-line 3
++changed 3
++added 4
+ line 5
+@@ -20,2 +21,3 @@ class Login {
+ line 21
++added 22
+ line 23
+diff --git a/src/Old.java b/src/Old.java
+deleted file mode 100644
+--- a/src/Old.java
++++ /dev/null
+@@ -1 +0,0 @@
+-class Old {}
+--- docs/a.py	2026-10-01 10:00:00
++++ docs/a.py	2026-10-02 10:00:00
+@@ -1 +1 @@
+-print(1)
+\ No newline at end of file
++print(2)
+\ No newline at end of file
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	files, err := parseUnifiedDiff(sampleDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 changed files, got %d", len(files))
+	}
+
+	login := files[0]
+	if login.Path != "src/Login.java" {
+		t.Errorf("unexpected path %q", login.Path)
+	}
+	expected := map[int]string{3: "changed 3", 4: "added 4", 22: "added 22"}
+	if !reflect.DeepEqual(login.Added, expected) {
+		t.Errorf("added lines = %v, want %v", login.Added, expected)
+	}
+	if !reflect.DeepEqual(login.Hunks, []diffHunk{{NewStart: 2, NewLines: 4}, {NewStart: 21, NewLines: 3}}) {
+		t.Errorf("unexpected hunks: %+v", login.Hunks)
+	}
+
+	if files[1].Path != "docs/a.py" || files[1].Added[1] != "print(2)" {
+		t.Errorf("unexpected plain diff -u file: %+v", files[1])
+	}
+
+	if _, err := parseUnifiedDiff("+++ b/A.java\n@@ -1 +1 @@\n?garbage\n"); err == nil {
+		t.Error("expected an error for a malformed hunk")
+	}
+}
+
+func TestDiffWindows(t *testing.T) {
+	file := &diffFile{Hunks: []diffHunk{{NewStart: 5, NewLines: 3}, {NewStart: 40, NewLines: 2}, {NewStart: 100, NewLines: 4}}}
+
+	windows := file.windows(110)
+	expected := [][2]int{{1, 61}, {80, 110}}
+	if !reflect.DeepEqual(windows, expected) {
+		t.Errorf("windows = %v, want %v", windows, expected)
+	}
+}
+
+func TestAnalyzeDiff(t *testing.T) {
+	// The snippet sent to the LLM starts DIFF_CONTEXT_LINES above the hunk,
+	// at line 7, so snippet line 24 is new-file line 30
+	newFakeLLM(t, `[
+		{"title":"SQL Injection","severity":"HIGH","line_number":24},
+		{"title":"Weak Hash","severity":"LOW","line_number":1},
+		{"title":"Missing CSRF protection","severity":"MEDIUM"}
+	]`)
+	server := NewMCPServer()
+
+	lines := []string{}
+	for i := 1; i <= 60; i++ {
+		lines = append(lines, fmt.Sprintf("    int line%d = %d;", i, i))
+	}
+	lines[29] = `    String query = "SELECT * FROM users WHERE id = " + id;`
+	content := "public class Repo {\n" + strings.Join(lines[1:], "\n") + "\n}"
+
+	diff := "--- a/Repo.java\n+++ b/Repo.java\n@@ -27,7 +27,7 @@\n" +
+		" " + lines[26] + "\n " + lines[27] + "\n " + lines[28] + "\n" +
+		"-    String query = \"SELECT * FROM users WHERE id = ?\";\n" +
+		"+" + lines[29] + "\n" +
+		" " + lines[30] + "\n " + lines[31] + "\n " + lines[32] + "\n"
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "analyze_diff",
+		"arguments": map[string]interface{}{
+			"diff":  diff,
+			"files": []interface{}{map[string]interface{}{"path": "Repo.java", "content": content}},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_diff failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		StructuredContent struct {
+			Files []scanFileResult `json:"files"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	files := result.StructuredContent.Files
+	if len(files) != 1 || files[0].Result == nil {
+		t.Fatalf("unexpected analyze_diff result: %s", jsonData)
+	}
+	issues := files[0].Result.Issues
+	if len(issues) != 1 || issues[0].Title != "SQL Injection" || issues[0].LineNumber != 30 {
+		t.Errorf("expected only the issue on the changed line, mapped to line 30, got %+v", issues)
+	}
+	if files[0].Result.AnalysisMetadata.IssuesFound != 1 || files[0].Result.AnalysisMetadata.HighCount != 1 {
+		t.Errorf("expected metadata to count only the kept issue: %+v", files[0].Result.AnalysisMetadata)
+	}
+	if _, issue := server.reports.findIssue("test", issues[0].Fingerprint); issue == nil {
+		t.Error("expected the diff finding to be stored for lookup by fingerprint")
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "analyze_diff",
+		"arguments": map[string]interface{}{
+			"diff":  diff,
+			"files": []interface{}{map[string]interface{}{"path": "Repo.java", "content": "public class Repo {}"}},
+		},
+	})
+	jsonData, _ = json.Marshal(response.Result)
+	if response.Error != nil || !strings.Contains(string(jsonData), `"isError":true`) {
+		t.Errorf("expected contents that do not match the diff to fail the file, got %s", jsonData)
+	}
+}
+
+func TestAnalyzeDiffBlockComment(t *testing.T) {
+	// The fake LLM reports the line of the query in the code it was sent,
+	// as a real one would
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.Unmarshal(body, &request)
+
+		_, code, _ := strings.Cut(request.Messages[len(request.Messages)-1].Content, "Code to analyze:\n```\n")
+		lineNumber := 0
+		for i, line := range strings.Split(code, "\n") {
+			if strings.Contains(line, "SELECT") {
+				lineNumber = i + 1
+			}
+		}
+
+		content := fmt.Sprintf(`[{"title":"SQL Injection","severity":"HIGH","line_number":%d}]`, lineNumber)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	defer llm.Close()
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+	server := NewMCPServer()
+
+	lines := []string{
+		"public class Repo {",
+		"    /*",
+		"     * Looks a user up by id.",
+		"     * The id comes from the request.",
+		"     */",
+		"    void find(String id) {",
+		`        String query = "SELECT * FROM users WHERE id = " + id;`,
+		"    }",
+		"}",
+	}
+	diff := "--- a/Repo.java\n+++ b/Repo.java\n@@ -1,9 +1,9 @@\n"
+	for i, line := range lines {
+		if i == 6 {
+			diff += "-        String query = \"SELECT * FROM users WHERE id = ?\";\n+" + line + "\n"
+			continue
+		}
+		diff += " " + line + "\n"
+	}
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "analyze_diff",
+		"arguments": map[string]interface{}{
+			"diff":  diff,
+			"files": []interface{}{map[string]interface{}{"path": "Repo.java", "content": strings.Join(lines, "\n")}},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_diff failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		StructuredContent struct {
+			Files []scanFileResult `json:"files"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	files := result.StructuredContent.Files
+	if len(files) != 1 || files[0].Result == nil || len(files[0].Result.Issues) != 1 || files[0].Result.Issues[0].LineNumber != 7 {
+		t.Errorf("expected the issue on the added line 7 below the block comment, got %s", jsonData)
+	}
+}
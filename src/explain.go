@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+// EXPLAIN_ISSUE_PROMPT asks the LLM for a deep-dive explanation of one
+// finding, in the sections listed by explanationSections
+const EXPLAIN_ISSUE_PROMPT = `Explain the security finding below to a junior developer who has to fix it.
+
+Answer in markdown with exactly these four sections:
+
+## Attack Scenario
+Step by step, how an attacker reaches and exploits the vulnerable code. Include a concrete example of malicious input (request, payload or file) and what it achieves.
+
+## Why the Code Is Exploitable
+Point at the exact lines and explain which missing check, unsafe API or data flow makes the attack possible.
+
+## CWE Background
+The CWE entry (ID and name) that best describes the weakness, how this class of vulnerability works in general, and how common and severe it is.
+
+## How the Remediation Fixes It
+Explain why the proposed remediation stops the attack above, show the corrected code, and mention any cases the remediation does not cover.
+
+Refer to the code actually shown; do not invent functions that are not in it.`
+
+// explanationSections maps the headings requested by EXPLAIN_ISSUE_PROMPT to
+// the structuredContent fields they are returned in
+var explanationSections = []struct {
+	Heading string
+	Field   string
+}{
+	{"Attack Scenario", "attack_scenario"},
+	{"Why the Code Is Exploitable", "exploitability"},
+	{"CWE Background", "cwe_background"},
+	{"How the Remediation Fixes It", "remediation"},
+}
+
+// explainIssue asks the LLM to explain issue in code, returning the markdown
+// explanation and the provider that wrote it
+func (s *MCPServer) explainIssue(ctx context.Context, language models.LanguageType, issue models.SecurityIssue, code string) (string, string, error) {
+	var finding strings.Builder
+	finding.WriteString(fmt.Sprintf("Finding: %s\nSeverity: %s\n", issue.Title, issue.Severity))
+	if issue.LineNumber > 0 {
+		finding.WriteString(fmt.Sprintf("Line: %d\n", issue.LineNumber))
+	}
+	if issue.Description != "" {
+		finding.WriteString(fmt.Sprintf("Description: %s\n", issue.Description))
+	}
+	if issue.CodeSnippet != "" {
+		finding.WriteString(fmt.Sprintf("Flagged code: %s\n", issue.CodeSnippet))
+	}
+	if issue.Remediation != "" {
+		finding.WriteString(fmt.Sprintf("Proposed remediation: %s\n", issue.Remediation))
+	}
+	if len(issue.References) > 0 {
+		finding.WriteString(fmt.Sprintf("References: %s\n", strings.Join(issue.References, ", ")))
+	}
+
+	messages := []services.Message{
+		{
+			Role:    "system",
+			Content: "You are an application security expert who explains vulnerabilities to developers clearly and precisely.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("%s\n\n%s\nThe %s code%s follows.\n\n%s",
+				EXPLAIN_ISSUE_PROMPT, finding.String(), language, fileContext(map[string]string{"file_path": issue.FilePath}), codeBlock(code)),
+		},
+	}
+
+	return s.llmService.Complete(ctx, messages)
+}
+
+// splitExplanation returns the text under each heading of
+// explanationSections, keyed by field. Missing sections are empty.
+func splitExplanation(markdown string) map[string]string {
+	sections := map[string]string{}
+	for _, section := range explanationSections {
+		sections[section.Field] = ""
+	}
+
+	field := ""
+	var body strings.Builder
+	flush := func() {
+		if field != "" {
+			sections[field] = strings.TrimSpace(body.String())
+		}
+		body.Reset()
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if heading, ok := strings.CutPrefix(strings.TrimSpace(line), "#"); ok {
+			heading = strings.TrimSpace(strings.TrimLeft(heading, "#"))
+			matched := ""
+			for _, section := range explanationSections {
+				if strings.EqualFold(heading, section.Heading) {
+					matched = section.Field
+				}
+			}
+			if matched != "" {
+				flush()
+				field = matched
+				continue
+			}
+		}
+		body.WriteString(line + "\n")
+	}
+	flush()
+
+	return sections
+}
+
+// handleExplainIssue handles the explain_issue tool
+func (s *MCPServer) handleExplainIssue(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	code, _ := args["code"].(string)
+	languageStr, _ := args["language"].(string)
+	fingerprint, _ := args["fingerprint"].(string)
+
+	var issue models.SecurityIssue
+	switch {
+	case args["issue"] != nil:
+		jsonData, _ := json.Marshal(args["issue"])
+		if err := json.Unmarshal(jsonData, &issue); err != nil {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid issue: %v", err)}
+		}
+	case fingerprint != "":
+		_, found := s.reports.findIssue(sessionFrom(ctx).id, fingerprint)
+		if found == nil {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("No stored report has an issue with fingerprint %s; pass the issue itself", fingerprint)}
+		}
+		issue = *found
+	default:
+		return nil, &MCPError{Code: -32602, Message: "Missing 'issue' or 'fingerprint' parameter"}
+	}
+	if filePath, ok := args["file_path"].(string); ok && filePath != "" {
+		issue.FilePath = filePath
+	}
+
+	language, _, mcpErr := s.resolveAnalyzer(ctx, languageStr, code, issue.FilePath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	explanation, provider, err := s.explainIssue(ctx, language, issue, code)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Explanation failed: %v", err)}
+	}
+
+	structured := map[string]interface{}{
+		"issue":    issue,
+		"provider": provider,
+	}
+	for field, text := range splitExplanation(explanation) {
+		structured[field] = text
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": fmt.Sprintf("# %s\n\n%s", issue.Title, strings.TrimSpace(explanation)),
+			},
+		},
+		"structuredContent": structured,
+	}
+	return response, nil
+}
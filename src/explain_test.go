@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sampleExplanation = `Here is the explanation.
+
+## Attack Scenario
+The attacker sends id=1 OR 1=1.
+
+## Why the Code Is Exploitable
+Line 3 concatenates id into the query.
+
+### CWE Background
+CWE-89: SQL Injection.
+
+## How the Remediation Fixes It
+A prepared statement binds id as data.`
+
+// newExplainLLM fakes an LLM that answers analysis requests with a finding
+// and explanation requests with sampleExplanation
+func newExplainLLM(t *testing.T) {
+	t.Helper()
+
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := `[{"title":"SQL Injection","severity":"HIGH","line_number":3,"code_snippet":"query + id"}]`
+		if strings.Contains(string(body), "Explain the security finding") {
+			content = sampleExplanation
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	t.Cleanup(llm.Close)
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+}
+
+func TestExplainIssue(t *testing.T) {
+	newExplainLLM(t)
+	server := NewMCPServer()
+
+	code := "public class Repo {\n  void find(String id) {\n    db.query(\"SELECT * FROM t WHERE id = \" + id);\n  }\n}"
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_security",
+		"arguments": map[string]interface{}{"code": code, "file_path": "Repo.java"},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_security failed: %s", response.Error.Message)
+	}
+	fingerprint := server.reports.list("test")[0].Result.Issues[0].Fingerprint
+
+	var explanation map[string]interface{}
+	err := callScanTool(t, server, "explain_issue", map[string]interface{}{"code": code, "fingerprint": fingerprint}, &explanation)
+	if err != nil {
+		t.Fatalf("explain_issue failed: %s", err.Message)
+	}
+	if explanation["attack_scenario"] != "The attacker sends id=1 OR 1=1." || explanation["cwe_background"] != "CWE-89: SQL Injection." {
+		t.Errorf("unexpected explanation: %+v", explanation)
+	}
+	if issue := explanation["issue"].(map[string]interface{}); issue["title"] != "SQL Injection" {
+		t.Errorf("expected the stored issue to be explained, got %+v", issue)
+	}
+
+	err = callScanTool(t, server, "explain_issue", map[string]interface{}{
+		"code":     code,
+		"language": "java",
+		"issue":    map[string]interface{}{"title": "Hardcoded password"},
+	}, &explanation)
+	if err != nil {
+		t.Fatalf("explain_issue with an issue failed: %s", err.Message)
+	}
+
+	if err := callScanTool(t, server, "explain_issue", map[string]interface{}{"code": code, "fingerprint": "0000000000000000"}, &explanation); err == nil {
+		t.Error("expected an unknown fingerprint to be rejected")
+	}
+}
+
+func TestExplainIssueFromProject(t *testing.T) {
+	newExplainLLM(t)
+	newScanWorkspace(t, "src/Repo.java")
+	server := NewMCPServer()
+
+	var report projectReport
+	if err := callScanTool(t, server, "analyze_project", map[string]interface{}{"path": "src"}, &report); err != nil {
+		t.Fatalf("analyze_project failed: %s", err.Message)
+	}
+	if len(report.Files) != 1 || report.Files[0].Result == nil || len(report.Files[0].Result.Issues) != 1 {
+		t.Fatalf("unexpected project report: %+v", report)
+	}
+	fingerprint := report.Files[0].Result.Issues[0].Fingerprint
+
+	var explanation map[string]interface{}
+	err := callScanTool(t, server, "explain_issue", map[string]interface{}{"code": "public class A {}", "language": "java", "fingerprint": fingerprint}, &explanation)
+	if err != nil {
+		t.Fatalf("explain_issue failed for a finding of analyze_project: %s", err.Message)
+	}
+	if issue := explanation["issue"].(map[string]interface{}); issue["title"] != "SQL Injection" {
+		t.Errorf("expected the project finding to be explained, got %+v", issue)
+	}
+}
+
+func TestSplitExplanation(t *testing.T) {
+	sections := splitExplanation("No headings here")
+	if len(sections) != len(explanationSections) || sections["attack_scenario"] != "" {
+		t.Errorf("expected empty sections for an unstructured reply, got %+v", sections)
+	}
+}
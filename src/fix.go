@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+// PATCH_URI_PREFIX names the patches returned by suggest_fix as embedded
+// resources
+const PATCH_URI_PREFIX = "aeyewire://patches/"
+
+// FIX_ISSUES_PROMPT asks the LLM for a minimal corrected version of the code
+const FIX_ISSUES_PROMPT = `Fix the security findings listed below in the code that follows.
+
+Rules:
+- Change only what is needed to fix the listed findings; do not refactor, reformat, rename or reorder unrelated code.
+- Keep the existing indentation, comments and line structure wherever possible.
+- Prefer the fix suggested in each finding's remediation, using libraries the code already depends on.
+- Return the complete corrected file in a single fenced code block and nothing else.`
+
+// codeFence delimits markdown code blocks
+const codeFence = "```"
+
+// extractCodeBlock returns the contents of the first fenced code block of an
+// LLM reply, or the whole reply when it has none
+func extractCodeBlock(reply string) string {
+	start := strings.Index(reply, codeFence)
+	if start < 0 {
+		return strings.TrimSpace(reply)
+	}
+
+	// Skip the info string, such as "java", on the opening fence line
+	body := reply[start+len(codeFence):]
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:]
+	} else {
+		return strings.TrimSpace(reply)
+	}
+
+	if end := strings.LastIndex(body, codeFence); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimRight(body, " \t\n")
+}
+
+// issuesFromArgs returns the findings given by the issues argument, or looked
+// up in the session's stored reports by the fingerprints argument
+func (s *MCPServer) issuesFromArgs(ctx context.Context, args map[string]interface{}) ([]models.SecurityIssue, *MCPError) {
+	issues := []models.SecurityIssue{}
+	if list, ok := args["issues"].([]interface{}); ok {
+		jsonData, _ := json.Marshal(list)
+		if err := json.Unmarshal(jsonData, &issues); err != nil {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid issues: %v", err)}
+		}
+	}
+	if list, ok := args["fingerprints"].([]interface{}); ok {
+		for _, item := range list {
+			fingerprint := item.(string)
+			_, issue := s.reports.findIssue(sessionFrom(ctx).id, fingerprint)
+			if issue == nil {
+				return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("No stored report has an issue with fingerprint %s; pass the issue itself", fingerprint)}
+			}
+			issues = append(issues, *issue)
+		}
+	}
+	if len(issues) == 0 {
+		return nil, &MCPError{Code: -32602, Message: "Missing 'issues' or 'fingerprints' parameter"}
+	}
+	return issues, nil
+}
+
+// suggestFix asks the LLM to fix issues in code and returns the corrected
+// code, the patch turning code into it, and the provider that wrote it. The
+// patch is checked to apply cleanly to code.
+func (s *MCPServer) suggestFix(ctx context.Context, language models.LanguageType, filePath string, code string, issues []models.SecurityIssue) (string, string, string, error) {
+	var findings strings.Builder
+	for i, issue := range issues {
+		findings.WriteString(fmt.Sprintf("%d. [%s] %s", i+1, issue.Severity, issue.Title))
+		if issue.LineNumber > 0 {
+			findings.WriteString(fmt.Sprintf(" (line %d)", issue.LineNumber))
+		}
+		if issue.Description != "" {
+			findings.WriteString(": " + issue.Description)
+		}
+		if issue.Remediation != "" {
+			findings.WriteString("\n   Remediation: " + issue.Remediation)
+		}
+		findings.WriteString("\n")
+	}
+
+	messages := []services.Message{
+		{
+			Role:    "system",
+			Content: "You are a security engineer who fixes vulnerabilities with the smallest correct change.",
+		},
+		{
+			Role: "user",
+			Content: fmt.Sprintf("%s\n\nFindings:\n%s\nThe %s code%s follows.\n\n%s",
+				FIX_ISSUES_PROMPT, findings.String(), language, fileContext(map[string]string{"file_path": filePath}), codeBlock(code)),
+		},
+	}
+
+	reply, provider, err := s.llmService.Complete(ctx, messages)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// Keep the original's final newline, which code blocks drop
+	fixed := strings.TrimRight(normalizeLineEndings(extractCodeBlock(reply)), "\n")
+	if strings.HasSuffix(code, "\n") {
+		fixed += "\n"
+	}
+
+	patch, err := unifiedDiff(patchName(filePath), code, fixed)
+	if err != nil {
+		return "", "", "", err
+	}
+	if patch == "" {
+		return "", "", "", fmt.Errorf("the LLM returned the code unchanged")
+	}
+	applied, err := applyPatch(code, patch)
+	if err != nil || applied != fixed {
+		return "", "", "", fmt.Errorf("the generated patch does not apply cleanly: %v", err)
+	}
+	return fixed, patch, provider, nil
+}
+
+// patchName is the file name used in patch headers
+func patchName(filePath string) string {
+	if filePath == "" {
+		return "code"
+	}
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(filePath)), "/")
+}
+
+// handleSuggestFix handles the suggest_fix tool
+func (s *MCPServer) handleSuggestFix(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	code, _ := args["code"].(string)
+	filePath, _ := args["file_path"].(string)
+	languageStr, _ := args["language"].(string)
+
+	issues, mcpErr := s.issuesFromArgs(ctx, args)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if filePath == "" {
+		filePath = issues[0].FilePath
+	}
+
+	language, _, mcpErr := s.resolveAnalyzer(ctx, languageStr, code, filePath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	fixed, patch, provider, err := s.suggestFix(ctx, language, filePath, code, issues)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Fix generation failed: %v", err)}
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": fmt.Sprintf("# Suggested Fix\n\nAddresses %d finding(s). The patch applies cleanly to the submitted code.\n\n```diff\n%s```\n", len(issues), patch),
+			},
+			{
+				"type": "resource",
+				"resource": map[string]interface{}{
+					"uri":      PATCH_URI_PREFIX + patchName(filePath) + ".patch",
+					"mimeType": "text/x-diff",
+					"text":     patch,
+				},
+			},
+		},
+		"structuredContent": map[string]interface{}{
+			"file_path":  filePath,
+			"patch":      patch,
+			"fixed_code": fixed,
+			"issues":     issues,
+			"provider":   provider,
+		},
+	}
+	return response, nil
+}
+
+// fixFile runs the fix CLI command: it analyzes a file and prints a patch
+// fixing every finding, ready for git apply. Progress goes to stderr so the
+// patch can be redirected.
+func fixFile(filePath string) {
+	code, err := readSourceFile(filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
+		os.Exit(1)
+	}
+
+	server := NewMCPServer()
+	language, analyzer, mcpErr := server.resolveAnalyzer(context.Background(), "", code, filePath)
+	if mcpErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", mcpErr.Message)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Analyzing %s as %s...\n", filePath, language)
+	result, err := analyzer.Analyze(context.Background(), code, filePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		os.Exit(1)
+	}
+	if len(result.Issues) == 0 {
+		fmt.Fprintln(os.Stderr, "No security issues found.")
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Fixing %d issue(s)...\n", len(result.Issues))
+	_, patch, _, err := server.suggestFix(context.Background(), language, filePath, code, result.Issues)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fix generation failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Print(patch)
+}
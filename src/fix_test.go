@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSuggestFix(t *testing.T) {
+	code := "class Repo {\n  void find(String id) {\n    db.query(\"SELECT * FROM t WHERE id = \" + id);\n  }\n}\n"
+	fixed := "class Repo {\n  void find(String id) {\n    db.query(\"SELECT * FROM t WHERE id = ?\", id);\n  }\n}"
+	newFakeLLM(t, "Here is the fix:\n```java\n"+fixed+"\n```\nThe query is now parameterized.")
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "suggest_fix",
+		"arguments": map[string]interface{}{
+			"code":      code,
+			"file_path": "src/Repo.java",
+			"issues":    []interface{}{map[string]interface{}{"title": "SQL Injection", "line_number": 3.0, "remediation": "Use a parameterized query"}},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("suggest_fix failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Resource struct {
+				URI      string `json:"uri"`
+				MimeType string `json:"mimeType"`
+				Text     string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent struct {
+			Patch     string `json:"patch"`
+			FixedCode string `json:"fixed_code"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	patch := result.StructuredContent.Patch
+	if !strings.HasPrefix(patch, "--- a/src/Repo.java\n+++ b/src/Repo.java\n@@ -1,5 +1,5 @@\n") ||
+		!strings.Contains(patch, "-    db.query(\"SELECT * FROM t WHERE id = \" + id);\n+    db.query(\"SELECT * FROM t WHERE id = ?\", id);\n") {
+		t.Errorf("unexpected patch:\n%s", patch)
+	}
+	if applied, err := applyPatch(code, patch); err != nil || applied != fixed+"\n" {
+		t.Errorf("patch does not reproduce the fix: %v\n%s", err, applied)
+	}
+
+	if len(result.Content) != 2 || result.Content[1].Type != "resource" {
+		t.Fatalf("expected the patch as an embedded resource, got %s", jsonData)
+	}
+	resource := result.Content[1].Resource
+	if resource.URI != "aeyewire://patches/src/Repo.java.patch" || resource.MimeType != "text/x-diff" || resource.Text != patch {
+		t.Errorf("unexpected embedded resource: %+v", resource)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "suggest_fix",
+		"arguments": map[string]interface{}{"code": code, "language": "java"},
+	})
+	if response.Error == nil {
+		t.Error("expected a call without findings to be rejected")
+	}
+}
+
+func TestSuggestFixUnchanged(t *testing.T) {
+	code := "class A {}\n"
+	newFakeLLM(t, "```java\nclass A {}\n```")
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "suggest_fix",
+		"arguments": map[string]interface{}{
+			"code":     code,
+			"language": "java",
+			"issues":   []interface{}{map[string]interface{}{"title": "Weak Hash"}},
+		},
+	})
+	if response.Error == nil || !strings.Contains(response.Error.Message, "unchanged") {
+		t.Errorf("expected an unchanged fix to be reported, got %+v", response)
+	}
+}
+
+func TestExtractCodeBlock(t *testing.T) {
+	tests := map[string]string{
+		"```java\nclass A {}\n```":         "class A {}",
+		"Fixed:\n```\na\nb\n```\nDone.":    "a\nb",
+		"class A {}\n":                     "class A {}",
+		"```go\nx := \"```\"\ny\n```\nend": "x := \"```\"\ny",
+	}
+	for reply, expected := range tests {
+		if got := extractCodeBlock(reply); got != expected {
+			t.Errorf("extractCodeBlock(%q) = %q, want %q", reply, got, expected)
+		}
+	}
+}
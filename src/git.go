@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// blameHeader matches the first line of a porcelain blame entry:
+// "<sha> <original line> <final line> [<group size>]"
+var blameHeader = regexp.MustCompile(`^([0-9a-f]{40}) \d+ (\d+)`)
+
+// gitScan selects the changes a git-aware scan reviews: the working tree
+// against HEAD (including untracked files), the index against HEAD, or the
+// commits of a base..head range
+type gitScan struct {
+	Staged bool
+	Range  string
+
+	// commits and headCommit are Range with its revisions resolved to
+	// commit IDs by resolve, and the head of it. Only these reach git, so a
+	// range cannot smuggle in options.
+	commits    string
+	headCommit string
+}
+
+// describe names the changes for reports
+func (scan gitScan) describe() string {
+	switch {
+	case scan.Range != "":
+		return "commits " + scan.Range
+	case scan.Staged:
+		return "staged changes"
+	default:
+		return "working tree changes"
+	}
+}
+
+// resolve checks the revisions of a <base>..<head> or <base>...<head> range
+// and resolves them to the commits they name in the repository holding dir.
+// An empty side stands for HEAD, as in git.
+func (scan *gitScan) resolve(ctx context.Context, dir string) error {
+	if scan.Range == "" {
+		return nil
+	}
+
+	separator := ".."
+	if strings.Contains(scan.Range, "...") {
+		separator = "..."
+	}
+	base, head, ok := strings.Cut(scan.Range, separator)
+	if !ok || strings.Contains(head, "..") {
+		return fmt.Errorf("invalid range %q: expected <base>..<head>", scan.Range)
+	}
+
+	commits := []string{}
+	for _, revision := range []string{base, head} {
+		if revision == "" {
+			revision = "HEAD"
+		}
+		if strings.HasPrefix(revision, "-") {
+			return fmt.Errorf("invalid range %q: revisions cannot start with '-'", scan.Range)
+		}
+		output, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "--end-of-options", revision+"^{commit}")
+		if err != nil {
+			return fmt.Errorf("invalid range %q: unknown revision %s", scan.Range, revision)
+		}
+		commits = append(commits, strings.TrimSpace(string(output)))
+	}
+
+	scan.commits = commits[0] + separator + commits[1]
+	scan.headCommit = commits[1]
+	return nil
+}
+
+// runGit runs git in dir and returns its standard output
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir, "-c", "core.quotepath=off"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return output, nil
+}
+
+// gitChanges lists the files changed under dir, with their hunks. Paths are
+// relative to the repository top level, which is returned too. Files the
+// analyzers cannot handle, and files excluded by .aeyewireignore, are left
+// out; git has applied .gitignore already.
+func (s *MCPServer) gitChanges(ctx context.Context, dir string, scan gitScan) (string, []*diffFile, error) {
+	output, err := runGit(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	top := strings.TrimSpace(string(output))
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	switch {
+	case scan.Range != "":
+		args = append(args, "--end-of-options", scan.commits)
+	case scan.Staged:
+		args = append(args, "--cached")
+	default:
+		args = append(args, "HEAD")
+	}
+	args = append(args, "--", ".")
+
+	// Run in dir, so the pathspec limits the diff to it
+	output, err = runGit(ctx, dir, args...)
+	if err != nil {
+		return "", nil, err
+	}
+	files, err := parseUnifiedDiff(string(output))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if scan.Range == "" && !scan.Staged {
+		output, err = runGit(ctx, dir, "ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--", ".")
+		if err != nil {
+			return "", nil, err
+		}
+		for _, name := range strings.Split(string(output), "\x00") {
+			if name == "" {
+				continue
+			}
+			if file, err := untrackedFile(filepath.Join(top, filepath.FromSlash(name)), name); err == nil {
+				files = append(files, file)
+			}
+		}
+	}
+
+	ignore := newIgnoreMatcher(top, ANALYSIS_IGNORE_FILES)
+	sources := []*diffFile{}
+	for _, file := range files {
+		if s.languageDetector.DetectFromExtension(path.Base(file.Path)) == models.UNKNOWN || skippedPath(file.Path) || ignore.ignoredPath(file.Path) {
+			continue
+		}
+		sources = append(sources, file)
+	}
+	return top, sources, nil
+}
+
+// untrackedFile describes a new file not yet known to git as wholly added
+func untrackedFile(fullPath string, name string) (*diffFile, error) {
+	code, err := readSourceFile(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(code, "\n")
+	file := &diffFile{Path: name, Hunks: []diffHunk{{NewStart: 1, NewLines: len(lines)}}, Added: map[int]string{}}
+	for i, line := range lines {
+		file.Added[i+1] = line
+	}
+	return file, nil
+}
+
+// skippedPath reports whether a slash-separated path lies in a hidden,
+// dependency or build directory, which directory walks never enter
+func skippedPath(name string) bool {
+	dirs := strings.Split(name, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if strings.HasPrefix(dir, ".") || SKIPPED_DIRS[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// gitContents returns the post-change contents of a changed file: from the
+// working tree, the index, or the head of the range
+func gitContents(ctx context.Context, top string, scan gitScan, name string) (string, error) {
+	if scan.Range == "" && !scan.Staged {
+		return readSourceFile(filepath.Join(top, filepath.FromSlash(name)))
+	}
+
+	revision := ":" + name
+	if scan.Range != "" {
+		revision = scan.headCommit + ":" + name
+	}
+	output, err := runGit(ctx, top, "show", "--end-of-options", revision)
+	if err != nil {
+		return "", err
+	}
+	if len(output) > MAX_SOURCE_FILE_SIZE {
+		return "", fmt.Errorf("%s is larger than %d bytes", name, MAX_SOURCE_FILE_SIZE)
+	}
+	return decodeSource(output), nil
+}
+
+// blameIssues sets the commit that introduced each issue's line, as of the
+// head of the range. git blame does not take --end-of-options, so it is only
+// given the resolved head commit.
+func blameIssues(ctx context.Context, top string, scan gitScan, name string, issues []models.SecurityIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	args := []string{"blame", "--porcelain"}
+	for _, issue := range issues {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", issue.LineNumber, issue.LineNumber))
+	}
+	args = append(args, scan.headCommit, "--", name)
+
+	output, err := runGit(ctx, top, args...)
+	if err != nil {
+		return err
+	}
+
+	commits := map[int]string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if match := blameHeader.FindStringSubmatch(line); match != nil {
+			number, _ := strconv.Atoi(match[2])
+			commits[number] = match[1]
+		}
+	}
+	for i := range issues {
+		issues[i].Commit = commits[issues[i].LineNumber]
+	}
+	return nil
+}
+
+// scanGitChanges reviews the changes selected by scan in the repository
+// holding dir. Only issues on added or modified lines are reported; for a
+// range they are tagged with the commit that introduced them.
+func (s *MCPServer) scanGitChanges(ctx context.Context, dir string, scan gitScan) ([]*diffFile, []scanFileResult, error) {
+	if err := scan.resolve(ctx, dir); err != nil {
+		return nil, nil, err
+	}
+
+	top, changed, err := s.gitChanges(ctx, dir, scan)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]scanFileResult, len(changed))
+	for i, file := range changed {
+		code, err := gitContents(ctx, top, scan, file.Path)
+		if err != nil {
+			files[i] = scanFileResult{FilePath: file.Path, Error: err.Error()}
+			continue
+		}
+
+		files[i] = s.analyzeDiffFile(analyzers.ForUnit(ctx, i, len(changed)), "", file, code)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if files[i].Result != nil && scan.Range != "" {
+			if err := blameIssues(ctx, top, scan, file.Path, files[i].Result.Issues); err != nil {
+				files[i].Result.AnalysisMetadata.Errors = append(files[i].Result.AnalysisMetadata.Errors, fmt.Sprintf("Could not attribute issues to commits: %v", err))
+			}
+		}
+	}
+	return changed, files, nil
+}
+
+// handleScanGitChanges handles the scan_git_changes tool
+func (s *MCPServer) handleScanGitChanges(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	path, _ := args["path"].(string)
+	if path == "" {
+		path = "."
+	}
+	scan := gitScan{}
+	scan.Staged, _ = args["staged"].(bool)
+	scan.Range, _ = args["range"].(string)
+	if scan.Staged && scan.Range != "" {
+		return nil, &MCPError{Code: -32602, Message: "'staged' and 'range' cannot be combined"}
+	}
+
+	dir, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), path)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Not a directory: %s", path)}
+	}
+
+	changed, files, err := s.scanGitChanges(ctx, dir, scan)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Git scan failed: %v", err)}
+	}
+
+	text := fmt.Sprintf("No supported source files changed in %s.", scan.describe())
+	if len(files) > 0 {
+		text = fmt.Sprintf("**Changes**: %s\n\n", scan.describe()) + formatDiffReport(changed, files)
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": text,
+			},
+		},
+		"structuredContent": map[string]interface{}{"files": files},
+	}
+	return response, nil
+}
+
+// diffCommand runs the diff CLI command: aeyewire_mcp diff [--staged|<base>..<head>].
+// It exits with status 2 when issues are found, so it can gate commits and
+// pushes.
+func diffCommand(args []string) {
+	scan := gitScan{}
+	for _, arg := range args {
+		switch {
+		case arg == "--staged" || arg == "--cached":
+			scan.Staged = true
+		case strings.HasPrefix(arg, "-"):
+			fmt.Printf("Unknown option: %s\n", arg)
+			os.Exit(1)
+		default:
+			scan.Range = arg
+		}
+	}
+	if scan.Staged && scan.Range != "" {
+		fmt.Println("Error: --staged and a range cannot be combined")
+		os.Exit(1)
+	}
+
+	server := NewMCPServer()
+	fmt.Printf("Analyzing %s...\n\n", scan.describe())
+
+	changed, files, err := server.scanGitChanges(context.Background(), ".", scan)
+	if err != nil {
+		fmt.Printf("Analysis failed: %v\n", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("No supported source files changed")
+		return
+	}
+
+	fmt.Println(formatDiffReport(changed, files))
+	for _, file := range files {
+		if file.Result != nil && len(file.Result.Issues) > 0 {
+			os.Exit(2)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newGitWorkspace creates a repository with one commit holding A.java and
+// makes it the working directory
+func newGitWorkspace(t *testing.T) (string, func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	workspace := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = workspace
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	write := func(name string, content string) {
+		os.MkdirAll(filepath.Dir(filepath.Join(workspace, name)), 0o755)
+		os.WriteFile(filepath.Join(workspace, name), []byte(content), 0o644)
+	}
+
+	git("init", "-q")
+	write("A.java", "class A {\n  int a;\n}\n")
+	write(".aeyewireignore", "generated/\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "Initial commit")
+
+	t.Chdir(workspace)
+	return workspace, git
+}
+
+// callGitScan calls scan_git_changes and returns the analyzed files
+func callGitScan(t *testing.T, server *MCPServer, arguments map[string]interface{}) []scanFileResult {
+	t.Helper()
+
+	var result struct {
+		Files []scanFileResult `json:"files"`
+	}
+	if err := callScanTool(t, server, "scan_git_changes", arguments, &result); err != nil {
+		t.Fatalf("scan_git_changes failed: %s", err.Message)
+	}
+	return result.Files
+}
+
+func TestScanGitChanges(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":2}]`)
+	workspace, git := newGitWorkspace(t)
+	server := NewMCPServer()
+
+	os.WriteFile(filepath.Join(workspace, "A.java"), []byte("class A {\n  String q = \"SELECT \" + id;\n}\n"), 0o644)
+	git("commit", "-q", "-am", "Build query")
+	commit := git("rev-parse", "HEAD")
+
+	files := callGitScan(t, server, map[string]interface{}{"range": "HEAD~1..HEAD"})
+	if len(files) != 1 || files[0].Result == nil || len(files[0].Result.Issues) != 1 {
+		t.Fatalf("unexpected range scan: %+v", files)
+	}
+	if issue := files[0].Result.Issues[0]; issue.LineNumber != 2 || issue.Commit != commit {
+		t.Errorf("expected the issue on line 2 to be tagged with %s, got %+v", commit, issue)
+	}
+
+	// Working tree: a modified file, an untracked file, an ignored file and
+	// a file no analyzer handles
+	os.WriteFile(filepath.Join(workspace, "A.java"), []byte("class A {\n  String q = \"DELETE \" + id;\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "B.java"), []byte("class B {\n  int b;\n}\n"), 0o644)
+	os.MkdirAll(filepath.Join(workspace, "generated"), 0o755)
+	os.WriteFile(filepath.Join(workspace, "generated", "G.java"), []byte("class G {}\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "notes.txt"), []byte("notes\n"), 0o644)
+
+	files = callGitScan(t, server, map[string]interface{}{})
+	if len(files) != 2 || files[0].FilePath != "A.java" || files[1].FilePath != "B.java" {
+		t.Fatalf("expected A.java and the untracked B.java, got %+v", files)
+	}
+	for _, file := range files {
+		if file.Result == nil || len(file.Result.Issues) != 1 || file.Result.Issues[0].Commit != "" {
+			t.Errorf("unexpected working tree result for %s: %+v", file.FilePath, file)
+		}
+	}
+
+	git("add", "A.java")
+	files = callGitScan(t, server, map[string]interface{}{"staged": true})
+	if len(files) != 1 || files[0].FilePath != "A.java" {
+		t.Errorf("expected only the staged A.java, got %+v", files)
+	}
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "scan_git_changes",
+		"arguments": map[string]interface{}{"staged": true, "range": "HEAD~1..HEAD"},
+	})
+	if response.Error == nil {
+		t.Error("expected staged and range together to be rejected")
+	}
+}
+
+func TestScanGitChangesOutsideRepository(t *testing.T) {
+	newScanWorkspace(t, "A.java")
+	server := NewMCPServer()
+
+	if _, _, err := server.scanGitChanges(context.Background(), ".", gitScan{}); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "scan_git_changes",
+		"arguments": map[string]interface{}{},
+	})
+	jsonData, _ := json.Marshal(response)
+	if response.Error == nil || !strings.Contains(response.Error.Message, "git") {
+		t.Errorf("expected a git error, got %s", jsonData)
+	}
+}
+
+func TestScanGitChangesRejectsOptions(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	workspace, _ := newGitWorkspace(t)
+	server := NewMCPServer()
+	output := filepath.Join(t.TempDir(), "x")
+
+	for _, gitRange := range []string{"--output=" + output + "..", "HEAD..--output=" + output, "..-p", "HEAD..HEAD..HEAD", "HEAD..nope"} {
+		scan := gitScan{Range: gitRange}
+		if _, _, err := server.scanGitChanges(context.Background(), workspace, scan); err == nil {
+			t.Errorf("expected range %q to be rejected", gitRange)
+		}
+	}
+	if _, err := os.Stat(output); err == nil {
+		t.Error("a range was passed to git as an option")
+	}
+
+	// Empty sides stand for HEAD, and three-dot ranges are accepted
+	for _, gitRange := range []string{"HEAD..", "..HEAD", "HEAD...HEAD"} {
+		if _, _, err := server.scanGitChanges(context.Background(), workspace, gitScan{Range: gitRange}); err != nil {
+			t.Errorf("range %q failed: %v", gitRange, err)
+		}
+	}
+}
+
+func TestScanGitChangesTrackedIgnoredFile(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	workspace, git := newGitWorkspace(t)
+	server := NewMCPServer()
+
+	// Vendor.java is ignored but was force-added, so git still reports it
+	os.WriteFile(filepath.Join(workspace, ".gitignore"), []byte("Vendor.java\nUntracked.java\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "Vendor.java"), []byte("class Vendor {}\n"), 0o644)
+	git("add", ".gitignore")
+	git("add", "-f", "Vendor.java")
+	git("commit", "-q", "-m", "Vendor a class")
+
+	os.WriteFile(filepath.Join(workspace, "Vendor.java"), []byte("class Vendor {\n  int v;\n}\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "Untracked.java"), []byte("class Untracked {}\n"), 0o644)
+
+	files := callGitScan(t, server, map[string]interface{}{})
+	if len(files) != 1 || files[0].FilePath != "Vendor.java" {
+		t.Errorf("expected only the tracked Vendor.java, got %+v", files)
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MCP_ENDPOINT is the path of the Streamable HTTP endpoint
+	MCP_ENDPOINT = "/mcp"
+
+	// SESSION_HEADER carries the session ID assigned on initialize
+	SESSION_HEADER = "Mcp-Session-Id"
+
+	// PROTOCOL_VERSION_HEADER carries the negotiated protocol version
+	PROTOCOL_VERSION_HEADER = "MCP-Protocol-Version"
+
+	// MAX_REQUEST_BODY matches the line limit of the stdio transport
+	MAX_REQUEST_BODY = 10 * 1024 * 1024
+
+	// SSE_KEEPALIVE keeps idle event streams open through proxies
+	SSE_KEEPALIVE = 30 * time.Second
+
+	// SESSION_IDLE_TIMEOUT is how long a session may go unused, with no
+	// request running and no stream open, before it is closed
+	SESSION_IDLE_TIMEOUT = 30 * time.Minute
+
+	// SESSION_SWEEP_INTERVAL is how often idle sessions are looked for
+	SESSION_SWEEP_INTERVAL = time.Minute
+
+	// DEFAULT_MAX_SESSIONS caps live sessions unless MCP_MAX_SESSIONS is set
+	DEFAULT_MAX_SESSIONS = 100
+)
+
+// httpTransport serves MCP over the Streamable HTTP transport: clients POST
+// JSON-RPC messages, may open a GET event stream for server-initiated
+// messages, and identify themselves with the Mcp-Session-Id header.
+type httpTransport struct {
+	server *MCPServer
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*session
+
+	// idleTimeout and maxSessions bound the sessions clients leave behind
+	// without a DELETE
+	idleTimeout time.Duration
+	maxSessions int
+
+	// root is the only directory sessions may read files from, set by
+	// MCP_HTTP_ROOT. When empty, path-based operations are refused.
+	root string
+
+	// token, set by MCP_HTTP_TOKEN, must be sent as a bearer token on every
+	// request when it is not empty
+	token string
+}
+
+// newHTTPTransport creates a Streamable HTTP transport for server
+func newHTTPTransport(server *MCPServer) *httpTransport {
+	root := os.Getenv("MCP_HTTP_ROOT")
+	if root != "" {
+		root = resolveExisting(root)
+	}
+
+	return &httpTransport{
+		server:      server,
+		sessions:    make(map[string]*session),
+		idleTimeout: SESSION_IDLE_TIMEOUT,
+		maxSessions: positiveIntFromEnv("MCP_MAX_SESSIONS", DEFAULT_MAX_SESSIONS),
+		root:        root,
+		token:       os.Getenv("MCP_HTTP_TOKEN"),
+	}
+}
+
+// RunHTTP starts the MCP server on the Streamable HTTP transport
+func (s *MCPServer) RunHTTP(addr string) error {
+	transport := newHTTPTransport(s)
+	go transport.sweepSessions(SESSION_SWEEP_INTERVAL)
+
+	mux := http.NewServeMux()
+	mux.Handle(MCP_ENDPOINT, transport)
+
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if transport.token == "" {
+		fmt.Fprintln(os.Stderr, "Warning: MCP_HTTP_TOKEN is not set, so any client that can reach the server can use it")
+	}
+	if transport.root == "" {
+		fmt.Fprintln(os.Stderr, "File access is disabled; set MCP_HTTP_ROOT to the directory clients may analyze")
+	}
+	fmt.Fprintf(os.Stderr, "AeyeWire MCP listening on %s%s\n", addr, MCP_ENDPOINT)
+	return httpServer.ListenAndServe()
+}
+
+// ServeHTTP dispatches requests on the MCP endpoint by HTTP method
+func (t *httpTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !validOrigin(r) {
+		http.Error(w, "Forbidden: invalid Origin", http.StatusForbidden)
+		return
+	}
+	if !t.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleGet(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized reports whether r carries the configured bearer token. Every
+// request is authorized when no token is configured.
+func (t *httpTransport) authorized(r *http.Request) bool {
+	if t.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(t.token)) == 1
+}
+
+// handlePost handles a JSON-RPC message or batch sent by the client.
+// Requests are answered with an event stream when the client accepts one,
+// so progress notifications can precede the response; otherwise with a JSON
+// body. Payloads with only notifications or responses get 202 Accepted.
+func (t *httpTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAX_REQUEST_BODY))
+	if err != nil {
+		writeJSON(w, http.StatusRequestEntityTooLarge, newErrorResponse(nil, -32600, fmt.Sprintf("Invalid request: %v", err)))
+		return
+	}
+
+	messages, batch, parseErr := parseMessages(body)
+	if parseErr != nil {
+		writeJSON(w, http.StatusBadRequest, parseErr)
+		return
+	}
+
+	if version := r.Header.Get(PROTOCOL_VERSION_HEADER); version != "" && negotiateProtocolVersion(version) != version {
+		http.Error(w, "Bad Request: unsupported "+PROTOCOL_VERSION_HEADER+" "+version, http.StatusBadRequest)
+		return
+	}
+
+	// initialize opens a new session and must not be part of a batch
+	var sess *session
+	if !batch && messages[0].Method == "initialize" {
+		if sess = t.createSession(); sess == nil {
+			writeJSON(w, http.StatusServiceUnavailable, newErrorResponse(messages[0].ID, -32603, "Too many open sessions; close unused sessions or try again later"))
+			return
+		}
+	} else if sess = t.lookupSession(w, r); sess == nil {
+		return
+	}
+	w.Header().Set(SESSION_HEADER, sess.id)
+	defer sess.touch()
+
+	if !hasRequests(messages) {
+		t.server.process(sess, messages, batch, sess.notify)()
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if !acceptsEventStream(r) {
+		reply := t.server.process(sess, messages, batch, sess.notify)()
+		if reply == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		writeJSON(w, http.StatusOK, reply)
+		return
+	}
+
+	stream := newSSEStream(w)
+	if stream == nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	if reply := t.server.process(sess, messages, batch, stream.send)(); reply != nil {
+		stream.send(reply)
+	}
+}
+
+// handleGet opens the event stream that carries server-initiated messages
+// for a session. A new stream replaces any previous one.
+func (t *httpTransport) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "Method not allowed: Accept must include text/event-stream", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sess := t.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	stream := newSSEStream(w)
+	if stream == nil {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	generation := sess.setSender(stream.send)
+	defer sess.touch()
+	defer stream.close()
+	defer sess.clearSender(generation)
+
+	ticker := time.NewTicker(SSE_KEEPALIVE)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stream.keepalive()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleDelete terminates a session, cancelling its in-flight requests and
+// resource subscriptions
+func (t *httpTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess := t.lookupSession(w, r)
+	if sess == nil {
+		return
+	}
+
+	t.sessionsMu.Lock()
+	delete(t.sessions, sess.id)
+	t.sessionsMu.Unlock()
+
+	t.closeSession(sess)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// closeSession cancels the in-flight requests, resource subscriptions and
+// scans of a session that was removed from the transport, and drops its
+// reports
+func (t *httpTransport) closeSession(sess *session) {
+	sess.close()
+	t.server.scans.forget(sess.id)
+	t.server.reports.forget(sess.id)
+}
+
+// createSession registers a new session with a random ID. It returns nil
+// when maxSessions are live even after closing idle ones.
+func (t *httpTransport) createSession() *session {
+	idBytes := make([]byte, 16)
+	rand.Read(idBytes)
+	sess := newSession(hex.EncodeToString(idBytes), nil)
+	sess.remote = true
+	sess.serverRoot = t.root
+
+	t.sessionsMu.Lock()
+	full := len(t.sessions) >= t.maxSessions
+	t.sessionsMu.Unlock()
+	if full {
+		t.expireIdleSessions(time.Now())
+	}
+
+	t.sessionsMu.Lock()
+	defer t.sessionsMu.Unlock()
+	if len(t.sessions) >= t.maxSessions {
+		return nil
+	}
+	t.sessions[sess.id] = sess
+	return sess
+}
+
+// sweepSessions closes idle sessions every interval, for as long as the
+// server runs
+func (t *httpTransport) sweepSessions(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		t.expireIdleSessions(now)
+	}
+}
+
+// expireIdleSessions closes the sessions idle for longer than idleTimeout
+// as of now and returns how many it closed
+func (t *httpTransport) expireIdleSessions(now time.Time) int {
+	// Checking a session may wait on its stream, so it is done without
+	// holding sessionsMu
+	t.sessionsMu.Lock()
+	sessions := make([]*session, 0, len(t.sessions))
+	for _, sess := range t.sessions {
+		sessions = append(sessions, sess)
+	}
+	t.sessionsMu.Unlock()
+
+	expired := []*session{}
+	for _, sess := range sessions {
+		if !sess.idle(now, t.idleTimeout) {
+			continue
+		}
+		t.sessionsMu.Lock()
+		if t.sessions[sess.id] == sess {
+			delete(t.sessions, sess.id)
+			expired = append(expired, sess)
+		}
+		t.sessionsMu.Unlock()
+	}
+
+	for _, sess := range expired {
+		t.closeSession(sess)
+	}
+	return len(expired)
+}
+
+// lookupSession returns the session named by the request header, writing
+// the error response and returning nil if it is missing or unknown
+func (t *httpTransport) lookupSession(w http.ResponseWriter, r *http.Request) *session {
+	id := r.Header.Get(SESSION_HEADER)
+	if id == "" {
+		http.Error(w, "Bad Request: missing "+SESSION_HEADER+" header", http.StatusBadRequest)
+		return nil
+	}
+
+	t.sessionsMu.Lock()
+	sess, ok := t.sessions[id]
+	t.sessionsMu.Unlock()
+
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil
+	}
+	sess.touch()
+	return sess
+}
+
+// validOrigin rejects browser requests whose Origin does not match the
+// requested host, which protects local servers against DNS rebinding
+func validOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return originURL.Host == r.Host
+}
+
+// acceptsEventStream reports whether the client accepts text/event-stream
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// writeJSON writes message as a JSON response body
+func writeJSON(w http.ResponseWriter, status int, message interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(message)
+}
+
+// sseStream writes JSON-RPC messages as server-sent events
+type sseStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+}
+
+// newSSEStream starts an event stream response, or returns nil if the
+// response writer cannot flush
+func newSSEStream(w http.ResponseWriter) *sseStream {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseStream{w: w, flusher: flusher}
+}
+
+// send writes message as a single event
+func (st *sseStream) send(message interface{}) {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding message: %v\n", err)
+		return
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.closed {
+		return
+	}
+	fmt.Fprintf(st.w, "event: message\ndata: %s\n\n", jsonData)
+	st.flusher.Flush()
+}
+
+// close drops the messages sent after the handler owning the response
+// returns, such as notifications racing with the stream being replaced
+func (st *sseStream) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.closed = true
+}
+
+// keepalive writes an SSE comment so idle connections are not dropped
+func (st *sseStream) keepalive() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	fmt.Fprint(st.w, ": keepalive\n\n")
+	st.flusher.Flush()
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postMCP posts a JSON-RPC message to the transport with optional headers
+func postMCP(t *testing.T, url string, body string, headers map[string]string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// initializeHTTP opens a session on the transport and returns its ID
+func initializeHTTP(t *testing.T, url string) string {
+	t.Helper()
+
+	resp := postMCP(t, url, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize returned status %d", resp.StatusCode)
+	}
+
+	sessionID := resp.Header.Get(SESSION_HEADER)
+	if sessionID == "" {
+		t.Fatal("initialize did not assign a session ID")
+	}
+	return sessionID
+}
+
+func TestHTTPSessionLifecycle(t *testing.T) {
+	ts := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer ts.Close()
+
+	sessionID := initializeHTTP(t, ts.URL)
+
+	resp := postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, map[string]string{SESSION_HEADER: sessionID})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("tools/list returned status %d (%s)", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var response MCPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != nil || response.ID != float64(2) {
+		t.Errorf("unexpected tools/list response: %+v", response)
+	}
+
+	resp = postMCP(t, ts.URL, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, map[string]string{SESSION_HEADER: sessionID})
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification returned status %d, want 202", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL, nil)
+	req.Header.Set(SESSION_HEADER, sessionID)
+	deleteResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleteResp.Body.Close()
+
+	resp = postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`, map[string]string{SESSION_HEADER: sessionID})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request after DELETE returned status %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestHTTPExpiresIdleSessions(t *testing.T) {
+	transport := newHTTPTransport(NewMCPServer())
+	ts := httptest.NewServer(transport)
+	defer ts.Close()
+
+	idleID := initializeHTTP(t, ts.URL)
+	busyID := initializeHTTP(t, ts.URL)
+
+	transport.sessionsMu.Lock()
+	busy := transport.sessions[busyID]
+	transport.sessionsMu.Unlock()
+	_, finish, _ := busy.trackRequest(float64(7))
+	defer finish()
+
+	if expired := transport.expireIdleSessions(time.Now()); expired != 0 {
+		t.Fatalf("expired %d fresh session(s)", expired)
+	}
+	if expired := transport.expireIdleSessions(time.Now().Add(SESSION_IDLE_TIMEOUT + time.Minute)); expired != 1 {
+		t.Fatalf("expired %d session(s), want only the idle one", expired)
+	}
+
+	resp := postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, map[string]string{SESSION_HEADER: idleID})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("request to an expired session returned status %d, want 404", resp.StatusCode)
+	}
+	resp = postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, map[string]string{SESSION_HEADER: busyID})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("request to a busy session returned status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHTTPSessionLimit(t *testing.T) {
+	transport := newHTTPTransport(NewMCPServer())
+	transport.maxSessions = 2
+	ts := httptest.NewServer(transport)
+	defer ts.Close()
+
+	initializeHTTP(t, ts.URL)
+	initializeHTTP(t, ts.URL)
+
+	resp := postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, nil)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("initialize past the limit returned status %d, want 503", resp.StatusCode)
+	}
+	var response MCPResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == nil || response.Error.Code != -32603 {
+		t.Errorf("expected a -32603 error, got %+v", response)
+	}
+
+	// Idle sessions make room for new ones
+	transport.idleTimeout = -time.Second
+	initializeHTTP(t, ts.URL)
+}
+
+func TestHTTPStalledStream(t *testing.T) {
+	transport := newHTTPTransport(NewMCPServer())
+	ts := httptest.NewServer(transport)
+	defer ts.Close()
+
+	stalledID := initializeHTTP(t, ts.URL)
+	transport.sessionsMu.Lock()
+	stalled := transport.sessions[stalledID]
+	transport.sessionsMu.Unlock()
+
+	// A client that stops reading blocks every write to its stream
+	writing, unblock := make(chan struct{}), make(chan struct{})
+	defer close(unblock)
+	stalled.setSender(func(message interface{}) {
+		close(writing)
+		<-unblock
+	})
+	go stalled.notify(map[string]interface{}{"jsonrpc": "2.0", "method": "notifications/message"})
+	<-writing
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		transport.expireIdleSessions(time.Now())
+		otherID := initializeHTTP(t, ts.URL)
+		postMCP(t, ts.URL, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, map[string]string{SESSION_HEADER: otherID})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a stalled stream blocked other sessions")
+	}
+}
+
+func TestHTTPRootsRefreshWithoutStream(t *testing.T) {
+	server := NewMCPServer()
+	sess := newSession("http", nil)
+	sess.initialize(SUPPORTED_PROTOCOL_VERSIONS[0], map[string]interface{}{"roots": map[string]interface{}{}})
+
+	done := make(chan struct{})
+	go func() {
+		server.refreshRoots(sess)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("refreshRoots waited on a session with no event stream")
+	}
+}
+
+func TestHTTPFileAccess(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "A.java"), []byte("public class A {}"), 0o644)
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "B.java"), []byte("public class B {}"), 0o644)
+
+	// analyze sends analyze_file for path over a new session and returns
+	// the error, if any
+	analyze := func(ts *httptest.Server, path string) *MCPError {
+		sessionID := initializeHTTP(t, ts.URL)
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"analyze_file","arguments":{"path":%q,"language":"java"}}}`, path)
+		resp := postMCP(t, ts.URL, body, map[string]string{SESSION_HEADER: sessionID})
+
+		var response MCPResponse
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Error
+	}
+
+	// Client roots and the server's working directory are never used
+	disabled := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer disabled.Close()
+	if err := analyze(disabled, "roots.go"); err == nil || err.Code != -32602 || !strings.Contains(err.Message, "MCP_HTTP_ROOT") {
+		t.Errorf("expected file access to be disabled without MCP_HTTP_ROOT, got %+v", err)
+	}
+
+	t.Setenv("MCP_HTTP_ROOT", root)
+	confined := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer confined.Close()
+	if err := analyze(confined, "A.java"); err != nil {
+		t.Errorf("expected a file inside MCP_HTTP_ROOT to be analyzed, got %v", err.Message)
+	}
+	if err := analyze(confined, filepath.Join(outside, "B.java")); err == nil || !strings.Contains(err.Message, "outside the workspace roots") {
+		t.Errorf("expected a file outside MCP_HTTP_ROOT to be rejected, got %+v", err)
+	}
+}
+
+func TestHTTPBearerToken(t *testing.T) {
+	t.Setenv("MCP_HTTP_TOKEN", "secret")
+	ts := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer ts.Close()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		resp := postMCP(t, ts.URL, body, map[string]string{"Authorization": header})
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Authorization %q returned status %d, want 401", header, resp.StatusCode)
+		}
+	}
+
+	resp := postMCP(t, ts.URL, body, map[string]string{"Authorization": "Bearer secret"})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("the configured token returned status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHTTPRejectsInvalidRequests(t *testing.T) {
+	ts := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		body     string
+		headers  map[string]string
+		expected int
+	}{
+		{"Missing session", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, nil, http.StatusBadRequest},
+		{"Unknown session", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, map[string]string{SESSION_HEADER: "nope"}, http.StatusNotFound},
+		{"Parse error", `{not json}`, nil, http.StatusBadRequest},
+		{"Foreign origin", `{"jsonrpc":"2.0","id":1,"method":"initialize"}`, map[string]string{"Origin": "http://evil.example"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postMCP(t, ts.URL, tt.body, tt.headers)
+			if resp.StatusCode != tt.expected {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHTTPEventStreamResponse(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	ts := httptest.NewServer(newHTTPTransport(NewMCPServer()))
+	defer ts.Close()
+
+	sessionID := initializeHTTP(t, ts.URL)
+
+	body := `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","language":"java"},"_meta":{"progressToken":1}}}`
+	resp := postMCP(t, ts.URL, body, map[string]string{
+		SESSION_HEADER: sessionID,
+		"Accept":       "application/json, text/event-stream",
+	})
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	var methods []string
+	var response *MCPResponse
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var message struct {
+			MCPResponse
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			t.Fatalf("invalid event data %q: %v", data, err)
+		}
+		if message.Method != "" {
+			methods = append(methods, message.Method)
+			continue
+		}
+		response = &message.MCPResponse
+	}
+
+	if len(methods) == 0 || methods[0] != "notifications/progress" {
+		t.Errorf("expected progress notifications before the response, got %v", methods)
+	}
+	if response == nil || response.Error != nil || response.ID != float64(5) {
+		t.Errorf("unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IGNORE_FILES are read in every directory of a walk. .aeyewireignore uses
+// the .gitignore syntax and excludes files from analysis only.
+var IGNORE_FILES = []string{".gitignore", ".aeyewireignore"}
+
+// ANALYSIS_IGNORE_FILES are the ignore files applied to paths listed by git,
+// which has already applied .gitignore to the untracked ones and tracks the
+// others regardless of it
+var ANALYSIS_IGNORE_FILES = []string{".aeyewireignore"}
+
+// ignoreRule is one pattern line of an ignore file
+type ignoreRule struct {
+	// base is the directory holding the ignore file, relative to the walk
+	// root in slash form ("" for the root itself)
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns contain a slash and match the path relative to
+	// base; others match the file name at any depth
+	anchored bool
+}
+
+// ignoreMatcher decides which paths of a walk are excluded by the ignore
+// files found so far. Later rules take precedence, as in git.
+type ignoreMatcher struct {
+	root   string
+	files  []string
+	rules  []ignoreRule
+	loaded map[string]bool
+}
+
+// newIgnoreMatcher creates a matcher for a walk starting at root that reads
+// the ignore files named in files
+func newIgnoreMatcher(root string, files []string) *ignoreMatcher {
+	return &ignoreMatcher{root: root, files: files, loaded: map[string]bool{}}
+}
+
+// load reads the ignore files of dir, which must lie under the root. Each
+// directory is read once.
+func (m *ignoreMatcher) load(dir string) {
+	base, err := filepath.Rel(m.root, dir)
+	if err != nil {
+		return
+	}
+	base = filepath.ToSlash(base)
+	if base == "." {
+		base = ""
+	}
+	if m.loaded[base] {
+		return
+	}
+	m.loaded[base] = true
+
+	for _, name := range m.files {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnoreRule(base, scanner.Text()); ok {
+				m.rules = append(m.rules, rule)
+			}
+		}
+		file.Close()
+	}
+}
+
+// ignored reports whether the path, relative to the root in slash form, is
+// excluded
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !rule.anchored {
+			sub = path.Base(sub)
+		}
+
+		if rule.pattern.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// ignoredPath reports whether a file, relative to the root in slash form, is
+// excluded by the ignore files of the directories leading to it, which are
+// loaded as needed. It serves callers that get paths from elsewhere than a
+// walk, such as git.
+func (m *ignoreMatcher) ignoredPath(rel string) bool {
+	dirs := strings.Split(rel, "/")
+	dirs = dirs[:len(dirs)-1]
+
+	m.load(m.root)
+	for i := range dirs {
+		dir := strings.Join(dirs[:i+1], "/")
+		if m.ignored(dir, true) {
+			return true
+		}
+		m.load(filepath.Join(m.root, filepath.FromSlash(dir)))
+	}
+	return m.ignored(rel, false)
+}
+
+// parseIgnoreRule parses one line of an ignore file, returning false for
+// blank lines and comments
+func parseIgnoreRule(base string, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// \# and \! escape a leading special character
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	pattern, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.pattern = pattern
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob into an anchored regular
+// expression. "*" and "?" do not cross directories; "**" does.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	sb.WriteString("$")
+	return sb.String()
+}
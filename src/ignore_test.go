@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "web"), 0o755)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# build output\nbuild/\n*.gen.java\n!Keep.gen.java\n/Root.java\n"), 0o644)
+	os.WriteFile(filepath.Join(root, ".aeyewireignore"), []byte("**/fixtures/**\ndocs/*.py\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("vendor\nlib/*.js\n"), 0o644)
+
+	matcher := newIgnoreMatcher(root, IGNORE_FILES)
+	matcher.load(root)
+	matcher.load(filepath.Join(root, "web"))
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"build", true, true},
+		{"build", false, false},
+		{"src/build", true, true},
+		{"src/Model.gen.java", false, true},
+		{"src/Keep.gen.java", false, false},
+		{"Root.java", false, true},
+		{"src/Root.java", false, false},
+		{"test/fixtures/sql/Bad.java", false, true},
+		{"docs/conf.py", false, true},
+		{"docs/api/conf.py", false, false},
+		{"web/vendor", true, true},
+		{"web/lib/app.js", false, true},
+		{"web/lib/sub/app.js", false, false},
+		{"vendor", true, false},
+	}
+	for _, test := range tests {
+		if got := matcher.ignored(test.path, test.isDir); got != test.ignored {
+			t.Errorf("ignored(%q, %v) = %v, want %v", test.path, test.isDir, got, test.ignored)
+		}
+	}
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		glob  string
+		path  string
+		match bool
+	}{
+		{"*.java", "A.java", true},
+		{"*.java", "src/A.java", false},
+		{"src/**", "src/a/b.java", true},
+		{"**/test", "a/b/test", true},
+		{"**/test", "test", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"?.py", "ab.py", false},
+		{"[!a]*.go", "main.go", true},
+		{"[!a]*.go", "app.go", false},
+		{`\#notes`, "#notes", true},
+	}
+	for _, test := range tests {
+		rule, ok := parseIgnoreRule("", test.glob)
+		if !ok {
+			t.Fatalf("could not parse %q", test.glob)
+		}
+		if got := rule.pattern.MatchString(test.path); got != test.match {
+			t.Errorf("%q matching %q = %v, want %v", test.glob, test.path, got, test.match)
+		}
+	}
+}
+
+func TestIgnoredPath(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "web", "gen"), 0o755)
+	os.WriteFile(filepath.Join(root, ".aeyewireignore"), []byte("fixtures/\n"), 0o644)
+	os.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("gen/\n*.min.js\n"), 0o644)
+
+	matcher := newIgnoreMatcher(root, IGNORE_FILES)
+	for name, ignored := range map[string]bool{
+		"src/A.java":           false,
+		"test/fixtures/B.java": true,
+		"web/gen/api.ts":       true,
+		"web/app.min.js":       true,
+		"web/app.js":           false,
+		"other/gen/Keep.java":  false,
+		"other/app.min.js":     false,
+	} {
+		if got := matcher.ignoredPath(name); got != ignored {
+			t.Errorf("ignoredPath(%q) = %v, want %v", name, got, ignored)
+		}
+	}
+}
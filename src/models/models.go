@@ -33,6 +33,24 @@ type SecurityIssue struct {
 	CodeSnippet  string        `json:"code_snippet"`
 	Remediation  string        `json:"remediation"`
 	References   []string      `json:"references"`
+	// Commit that introduced the line, set by git-aware scans of a range
+	Commit       string        `json:"commit,omitempty"`
+	// Fingerprint identifies the issue across analyses, unlike ID
+	Fingerprint  string        `json:"fingerprint,omitempty"`
+	// RuleID is the ID of the analyzer rule the issue violates, if known
+	RuleID       string        `json:"rule_id,omitempty"`
+}
+
+// SecurityRule describes one weakness an analyzer checks for
+type SecurityRule struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	Category     string        `json:"category"`
+	CWE          []string      `json:"cwe"`
+	OWASP        string        `json:"owasp,omitempty"`
+	Severity     SeverityLevel `json:"default_severity"`
+	// Prompt tells the LLM what code the rule flags
+	Prompt       string        `json:"prompt"`
 }
 
 // AnalysisRequest represents input for security analysis
@@ -51,6 +69,7 @@ type AnalysisMetadata struct {
 	MediumCount     int               `json:"medium_count"`
 	LowCount        int               `json:"low_count"`
 	DetectedLanguage LanguageType     `json:"detected_language"`
+	Provider        string            `json:"provider,omitempty"`
 	Errors          []string          `json:"errors,omitempty"`
 }
 
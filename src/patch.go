@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PATCH_CONTEXT_LINES is the number of unchanged lines around each hunk
+	// of a generated patch, as in diff -u
+	PATCH_CONTEXT_LINES = 3
+
+	// MAX_DIFF_EDITS bounds the edit search; beyond it the changed middle of
+	// the file is replaced as a whole rather than diffed line by line
+	MAX_DIFF_EDITS = 1000
+
+	// MAX_DIFF_LINES bounds the length of each side of a line diff
+	MAX_DIFF_LINES = 50000
+)
+
+// patchHunkHeader matches "@@ -oldStart[,oldLines] +newStart[,newLines] @@"
+var patchHunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// lineEdit is one line of an edit script: kept (' '), removed ('-') or added
+// ('+')
+type lineEdit struct {
+	Op   byte
+	Text string
+}
+
+// splitLines splits text into lines, reporting whether it ends with a newline
+func splitLines(text string) ([]string, bool) {
+	if text == "" {
+		return []string{}, true
+	}
+	trailing := strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), trailing
+}
+
+// diffLines returns a shortest edit script turning a into b, found with
+// Myers' algorithm after trimming the common prefix and suffix. It fails
+// when either side has more than MAX_DIFF_LINES lines.
+func diffLines(a []string, b []string) ([]lineEdit, error) {
+	if len(a) > MAX_DIFF_LINES || len(b) > MAX_DIFF_LINES {
+		return nil, fmt.Errorf("cannot diff %d lines against %d: the limit is %d lines", len(a), len(b), MAX_DIFF_LINES)
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := []lineEdit{}
+	for _, line := range a[:prefix] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, lineEdit{' ', line})
+	}
+	return edits, nil
+}
+
+// myers finds a shortest edit script with Myers' O(ND) algorithm, falling
+// back to replacing every line when more than MAX_DIFF_EDITS are needed.
+// Step d only reaches diagonals -d..d, so v is sized for the steps that can
+// run and each step saves just the diagonals the next one may read.
+func myers(a []string, b []string) []lineEdit {
+	n, m := len(a), len(b)
+	offset := min(n+m, MAX_DIFF_EDITS) + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+
+	for d := 0; d <= n+m; d++ {
+		if d > MAX_DIFF_EDITS {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrack walks the saved states of myers back from the end of both
+// sequences to build the edit script. trace[d] holds diagonals -d-1..d+1.
+func backtrack(a []string, b []string, trace [][]int) []lineEdit {
+	reversed := []lineEdit{}
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		if d == 0 {
+			prevX, prevY = 0, 0
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, lineEdit{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, lineEdit{'+', b[prevY]})
+			} else {
+				reversed = append(reversed, lineEdit{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]lineEdit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// replaceAll is the edit script removing every line of a and adding b
+func replaceAll(a []string, b []string) []lineEdit {
+	edits := []lineEdit{}
+	for _, line := range a {
+		edits = append(edits, lineEdit{'-', line})
+	}
+	for _, line := range b {
+		edits = append(edits, lineEdit{'+', line})
+	}
+	return edits
+}
+
+// unifiedDiff returns the unified diff turning original into changed, with
+// the file named name on both sides, or "" when they are equal. Both must
+// agree on whether they end with a newline. It fails when either is too long
+// to diff.
+func unifiedDiff(name string, original string, changed string) (string, error) {
+	a, trailing := splitLines(original)
+	b, _ := splitLines(changed)
+	edits, err := diffLines(a, b)
+	if err != nil {
+		return "", err
+	}
+	if !trailing {
+		edits = splitFinalContext(edits, len(a), len(b))
+	}
+
+	// Position of each edit in both files, so hunk headers can be computed
+	oldLine, newLine := make([]int, len(edits)), make([]int, len(edits))
+	changes := []int{}
+	for i, x, y := 0, 0, 0; i < len(edits); i++ {
+		oldLine[i], newLine[i] = x, y
+		switch edits[i].Op {
+		case ' ':
+			x++
+			y++
+		case '-':
+			x++
+			changes = append(changes, i)
+		case '+':
+			y++
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", name, name))
+
+	for i := 0; i < len(changes); {
+		// Grow the hunk while the next change is close enough to share context
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*PATCH_CONTEXT_LINES+1 {
+			j++
+		}
+		start := max(changes[i]-PATCH_CONTEXT_LINES, 0)
+		end := min(changes[j]+PATCH_CONTEXT_LINES, len(edits)-1)
+
+		oldCount, newCount := 0, 0
+		for _, edit := range edits[start : end+1] {
+			if edit.Op != '+' {
+				oldCount++
+			}
+			if edit.Op != '-' {
+				newCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount)))
+
+		for e := start; e <= end; e++ {
+			edit := edits[e]
+			sb.WriteString(string(edit.Op) + edit.Text + "\n")
+			// Mark the last line of a side when the file lacks a final newline
+			lastOld := edit.Op != '+' && oldLine[e] == len(a)-1
+			lastNew := edit.Op != '-' && newLine[e] == len(b)-1
+			if !trailing && (lastOld || lastNew) {
+				sb.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		i = j + 1
+	}
+
+	return sb.String(), nil
+}
+
+// splitFinalContext rewrites a context line that is the last line of only
+// one side as a removal and an addition, so the missing final newline can be
+// marked right after it. Every edit following such a line is on the other
+// side, so the removal goes before them and the addition after the removals.
+func splitFinalContext(edits []lineEdit, oldLen int, newLen int) []lineEdit {
+	for i, x, y := 0, 0, 0; i < len(edits); i++ {
+		edit := edits[i]
+		if edit.Op == ' ' && (x == oldLen-1) != (y == newLen-1) {
+			rest := edits[i+1:]
+			split := append([]lineEdit{}, edits[:i]...)
+			split = append(split, lineEdit{'-', edit.Text})
+			for _, e := range rest {
+				if e.Op == '-' {
+					split = append(split, e)
+				}
+			}
+			split = append(split, lineEdit{'+', edit.Text})
+			for _, e := range rest {
+				if e.Op == '+' {
+					split = append(split, e)
+				}
+			}
+			return split
+		}
+		if edit.Op != '+' {
+			x++
+		}
+		if edit.Op != '-' {
+			y++
+		}
+	}
+	return edits
+}
+
+// hunkRange formats one side of a hunk header; start is zero based
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		// An empty side names the line before the hunk
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// applyPatch applies a single-file unified diff to original, failing if any
+// context or removed line does not match exactly. The result ends with a
+// newline if original does.
+func applyPatch(original string, patch string) (string, error) {
+	source, trailing := splitLines(original)
+	result := []string{}
+	next := 0
+
+	lines, _ := splitLines(patch)
+	for i := 0; i < len(lines); i++ {
+		match := patchHunkHeader.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+
+		oldStart, _ := strconv.Atoi(match[1])
+		oldCount, newCount := 1, 1
+		if match[2] != "" {
+			oldCount, _ = strconv.Atoi(match[2])
+		}
+		if match[4] != "" {
+			newCount, _ = strconv.Atoi(match[4])
+		}
+		position := oldStart - 1
+		if oldCount == 0 {
+			position = oldStart
+		}
+		if position < next || position > len(source) {
+			return "", fmt.Errorf("hunk %q is out of order or beyond the end of the file", match[0])
+		}
+		result = append(result, source[next:position]...)
+		next = position
+
+		previous := byte(0)
+		for i+1 < len(lines) && (oldCount > 0 || newCount > 0 || strings.HasPrefix(lines[i+1], "\\")) {
+			i++
+			line := lines[i]
+			if line == "" {
+				line = " "
+			}
+
+			op, text := line[0], line[1:]
+			switch op {
+			case ' ', '-':
+				if next >= len(source) || source[next] != text {
+					return "", fmt.Errorf("line %d does not match the patch", next+1)
+				}
+				next++
+				oldCount--
+				if op == ' ' {
+					result = append(result, text)
+					newCount--
+				}
+			case '+':
+				result = append(result, text)
+				newCount--
+			case '\\':
+				// The marker may only follow the last line of a side, as
+				// git apply requires
+				lastOld := (previous == ' ' || previous == '-') && oldCount == 0
+				lastNew := (previous == ' ' || previous == '+') && newCount == 0
+				if !lastOld && !lastNew || previous == ' ' && !(lastOld && lastNew) {
+					return "", fmt.Errorf("misplaced end of file marker in hunk %q", match[0])
+				}
+			default:
+				return "", fmt.Errorf("unexpected line in hunk: %q", truncateLine(line))
+			}
+			previous = op
+		}
+		if oldCount != 0 || newCount != 0 {
+			return "", fmt.Errorf("hunk %q is truncated", match[0])
+		}
+	}
+	result = append(result, source[next:]...)
+
+	text := strings.Join(result, "\n")
+	if trailing && len(result) > 0 {
+		text += "\n"
+	}
+	return text, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	original := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	changed := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	expected := "--- a/x.java\n+++ b/x.java\n" +
+		"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+		"@@ -10,3 +10,4 @@\n j\n k\n l\n+m\n"
+	if patch, err := unifiedDiff("x.java", original, changed); err != nil || patch != expected {
+		t.Errorf("unexpected patch (%v):\n%s\nwant:\n%s", err, patch, expected)
+	}
+
+	if patch, err := unifiedDiff("x.java", original, original); err != nil || patch != "" {
+		t.Errorf("expected no patch for equal contents, got (%v):\n%s", err, patch)
+	}
+
+	patch, _ := unifiedDiff("x.java", "a\nb", "a\nc")
+	if !strings.HasSuffix(patch, "-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n") {
+		t.Errorf("expected missing final newlines to be marked, got:\n%s", patch)
+	}
+}
+
+func TestApplyPatchRoundTrip(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d", ""}
+	randomText := func() string {
+		lines := []string{}
+		for i := random.Intn(30); i > 0; i-- {
+			lines = append(lines, words[random.Intn(len(words))])
+		}
+		// Both sides must agree on the final newline, added below
+		return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	}
+
+	for i := 0; i < 500; i++ {
+		original, changed := randomText(), randomText()
+		if original == "" || changed == "" {
+			continue
+		}
+		if i%2 == 0 {
+			original += "\n"
+			changed += "\n"
+		}
+
+		patch, _ := unifiedDiff("f", original, changed)
+		applied, err := applyPatch(original, patch)
+		if err != nil {
+			t.Fatalf("patch does not apply: %v\noriginal: %q\nchanged: %q\n%s", err, original, changed, patch)
+		}
+		if applied != changed {
+			t.Fatalf("applied %q, want %q\n%s", applied, changed, patch)
+		}
+	}
+}
+
+func TestDiffLinesLimits(t *testing.T) {
+	// Nearly MAX_DIFF_EDITS edits, so the saved states are backtracked far
+	a, b := []string{}, []string{}
+	for i := 0; i < 2000; i++ {
+		a = append(a, fmt.Sprintf("line %d", i))
+		if i%5 == 0 {
+			b = append(b, fmt.Sprintf("changed %d", i))
+		} else {
+			b = append(b, a[i])
+		}
+	}
+	original, changed := strings.Join(a, "\n")+"\n", strings.Join(b, "\n")+"\n"
+	patch, err := unifiedDiff("f", original, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := applyPatch(original, patch); err != nil || applied != changed {
+		t.Fatalf("patch with %d edits does not round-trip: %v", 2*len(a)/5, err)
+	}
+
+	// Files that differ everywhere give up after MAX_DIFF_EDITS steps
+	// without keeping a copy of every diagonal per step
+	a, b = []string{}, []string{}
+	for i := 0; i < MAX_DIFF_LINES; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits, err := diffLines(a, b)
+	runtime.ReadMemStats(&after)
+	if err != nil || len(edits) != 2*MAX_DIFF_LINES {
+		t.Fatalf("expected every line to be replaced, got %d edits (%v)", len(edits), err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("diffing %d lines allocated %d MB", MAX_DIFF_LINES, allocated>>20)
+	}
+
+	if _, err := diffLines(append(a, "one more"), b); err == nil || !strings.Contains(err.Error(), "limit") {
+		t.Errorf("expected more than MAX_DIFF_LINES lines to be rejected, got %v", err)
+	}
+}
+
+func TestApplyPatchMismatch(t *testing.T) {
+	patch, _ := unifiedDiff("f", "a\nb\nc\n", "a\nB\nc\n")
+	if _, err := applyPatch("a\nx\nc\n", patch); err == nil {
+		t.Error("expected a patch whose removed line differs to fail")
+	}
+
+	misplaced := "--- a/f\n+++ b/f\n@@ -1,4 +1,2 @@\n a\n }\n\\ No newline at end of file\n-b\n-}\n\\ No newline at end of file\n"
+	if _, err := applyPatch("a\n}\nb\n}", misplaced); err == nil {
+		t.Error("expected an end of file marker inside a hunk to fail")
+	}
+}
+
+func TestUnifiedDiffAppliesWithGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	original := "class A {\n  void f() {\n    run(input);\n  }\n}"
+	os.WriteFile(filepath.Join(dir, "A.java"), []byte(original), 0o644)
+	patch, _ := unifiedDiff("A.java", original, "class A {\n  void f() {\n    run(sanitize(input));\n  }\n}")
+	os.WriteFile(filepath.Join(dir, "fix.patch"), []byte(patch), 0o644)
+
+	cmd := exec.Command("git", "apply", "--check", "fix.patch")
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("git apply rejected the patch: %v\n%s\n%s", err, output, patch)
+	}
+}
+
+func TestUnifiedDiffAppliesWithGitWithoutFinalNewline(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	cases := []struct{ original, changed string }{
+		{"a\n}\nb\n}", "a\n}"},
+		{"a\n}", "a\n}\nb\n}"},
+		{"a\nb\nc\nd", "a\nb"},
+		{"a\nb\nc\nd", "a\nB\nc\nd"},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "f"), []byte(c.original), 0o644)
+		patch, err := unifiedDiff("f", c.original, c.changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if applied, err := applyPatch(c.original, patch); err != nil || applied != c.changed {
+			t.Errorf("applied %q (%v), want %q\n%s", applied, err, c.changed, patch)
+		}
+		os.WriteFile(filepath.Join(dir, "fix.patch"), []byte(patch), 0o644)
+
+		cmd := exec.Command("git", "apply", "fix.patch")
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("git apply rejected the patch for %q: %v\n%s\n%s", c.original, err, output, patch)
+			continue
+		}
+		if applied, _ := os.ReadFile(filepath.Join(dir, "f")); string(applied) != c.changed {
+			t.Errorf("git apply produced %q, want %q", applied, c.changed)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// TOP_RISK_FILES is the length of the top-risk file list of a project report
+const TOP_RISK_FILES = 10
+
+// severityWeights rank files by risk: one critical finding outweighs several
+// low ones
+var severityWeights = map[models.SeverityLevel]int{
+	models.CRITICAL: 10,
+	models.HIGH:     5,
+	models.MEDIUM:   2,
+	models.LOW:      1,
+}
+
+// fileRisk summarizes the findings of one file
+type fileRisk struct {
+	FilePath      string `json:"file_path"`
+	RiskScore     int    `json:"risk_score"`
+	CriticalCount int    `json:"critical_count"`
+	HighCount     int    `json:"high_count"`
+	MediumCount   int    `json:"medium_count"`
+	LowCount      int    `json:"low_count"`
+}
+
+// projectReport aggregates the analysis of many files
+type projectReport struct {
+	Path          string           `json:"path"`
+	FilesAnalyzed int              `json:"files_analyzed"`
+	FilesFailed   int              `json:"files_failed"`
+	IssuesFound   int              `json:"issues_found"`
+	CriticalCount int              `json:"critical_count"`
+	HighCount     int              `json:"high_count"`
+	MediumCount   int              `json:"medium_count"`
+	LowCount      int              `json:"low_count"`
+	TopRiskFiles  []fileRisk       `json:"top_risk_files"`
+	Files         []scanFileResult `json:"files"`
+}
+
+// analyzeProject analyzes every source file under root, workers files at a
+// time, and aggregates the results. Progress is reported per file.
+func (s *MCPServer) analyzeProject(ctx context.Context, root string, workers int) (*projectReport, error) {
+	files := []string{}
+	err := s.walkSourceFiles(ctx, root, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]scanFileResult, len(files))
+	paths := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range paths {
+				results[i] = s.scanFile(analyzers.ForUnit(ctx, i, len(files)), files[i])
+				if results[i].Result != nil {
+					s.storeResult(sessionFrom(ctx).id, files[i], files[i], results[i].Result)
+				}
+			}
+		}()
+	}
+
+	for i := range files {
+		select {
+		case paths <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(paths)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return buildProjectReport(root, results), nil
+}
+
+// buildProjectReport aggregates per-file results, which it sorts by path.
+// File paths under path are shown relative to it.
+func buildProjectReport(path string, results []scanFileResult) *projectReport {
+	for i := range results {
+		if rel, err := filepath.Rel(path, results[i].FilePath); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			results[i].FilePath = filepath.ToSlash(rel)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].FilePath < results[j].FilePath })
+
+	report := &projectReport{Path: path, TopRiskFiles: []fileRisk{}, Files: results}
+	for _, file := range results {
+		if file.Result == nil {
+			report.FilesFailed++
+			continue
+		}
+		report.FilesAnalyzed++
+
+		metadata := file.Result.AnalysisMetadata
+		report.IssuesFound += len(file.Result.Issues)
+		report.CriticalCount += metadata.CriticalCount
+		report.HighCount += metadata.HighCount
+		report.MediumCount += metadata.MediumCount
+		report.LowCount += metadata.LowCount
+
+		if len(file.Result.Issues) == 0 {
+			continue
+		}
+		risk := fileRisk{
+			FilePath:      file.FilePath,
+			CriticalCount: metadata.CriticalCount,
+			HighCount:     metadata.HighCount,
+			MediumCount:   metadata.MediumCount,
+			LowCount:      metadata.LowCount,
+		}
+		for _, issue := range file.Result.Issues {
+			risk.RiskScore += severityWeights[issue.Severity]
+		}
+		report.TopRiskFiles = append(report.TopRiskFiles, risk)
+	}
+
+	sort.SliceStable(report.TopRiskFiles, func(i, j int) bool {
+		return report.TopRiskFiles[i].RiskScore > report.TopRiskFiles[j].RiskScore
+	})
+	if len(report.TopRiskFiles) > TOP_RISK_FILES {
+		report.TopRiskFiles = report.TopRiskFiles[:TOP_RISK_FILES]
+	}
+	return report
+}
+
+// formatProjectReport formats an aggregated report as markdown: a severity
+// summary, the top-risk files, then a section per file
+func formatProjectReport(report *projectReport) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("**Path**: %s\n", report.Path))
+	sb.WriteString(fmt.Sprintf("**Files Analyzed**: %d", report.FilesAnalyzed))
+	if report.FilesFailed > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d failed)", report.FilesFailed))
+	}
+	sb.WriteString(fmt.Sprintf("\n**Total Issues**: %d\n\n", report.IssuesFound))
+
+	sb.WriteString("## Severity Summary\n\n")
+	sb.WriteString("| Severity | Count |\n")
+	sb.WriteString("|----------|-------|\n")
+	sb.WriteString(fmt.Sprintf("| Critical | %d |\n", report.CriticalCount))
+	sb.WriteString(fmt.Sprintf("| High | %d |\n", report.HighCount))
+	sb.WriteString(fmt.Sprintf("| Medium | %d |\n", report.MediumCount))
+	sb.WriteString(fmt.Sprintf("| Low | %d |\n\n", report.LowCount))
+
+	if len(report.TopRiskFiles) > 0 {
+		sb.WriteString("## Top Risk Files\n\n")
+		for i, risk := range report.TopRiskFiles {
+			sb.WriteString(fmt.Sprintf("%d. **%s** (risk score %d: %d critical, %d high, %d medium, %d low)\n",
+				i+1, risk.FilePath, risk.RiskScore, risk.CriticalCount, risk.HighCount, risk.MediumCount, risk.LowCount))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Findings by File\n\n")
+	for _, file := range report.Files {
+		if file.Result == nil {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### %s (%s)\n\n", file.FilePath, file.Result.Language))
+		if len(file.Result.Issues) == 0 {
+			sb.WriteString("No security issues found.\n\n")
+			continue
+		}
+		writeIssueList(&sb, file.Result.Issues)
+		sb.WriteString("\n")
+	}
+
+	if report.FilesFailed > 0 {
+		sb.WriteString("## Errors\n\n")
+		for _, file := range report.Files {
+			if file.Error != "" {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", file.FilePath, file.Error))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// writeIssueList writes issues as a compact markdown list, one item per issue
+func writeIssueList(sb *strings.Builder, issues []models.SecurityIssue) {
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("- **[%s] %s**", issue.Severity, issue.Title))
+		if issue.LineNumber > 0 {
+			sb.WriteString(fmt.Sprintf(" (line %d)", issue.LineNumber))
+		}
+		if issue.Commit != "" {
+			sb.WriteString(fmt.Sprintf(" [commit %.7s]", issue.Commit))
+		}
+		if issue.Description != "" {
+			sb.WriteString(": " + issue.Description)
+		}
+		sb.WriteString("\n")
+		if issue.Remediation != "" {
+			sb.WriteString(fmt.Sprintf("  - Remediation: %s\n", issue.Remediation))
+		}
+	}
+}
+
+// handleAnalyzeProject handles the analyze_project tool
+func (s *MCPServer) handleAnalyzeProject(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	path, _ := args["path"].(string)
+	if path == "" {
+		path = "."
+	}
+
+	root, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), path)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Not a directory: %s", path)}
+	}
+
+	report, err := s.analyzeProject(ctx, root, s.scans.workers)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Project analysis failed: %v", err)}
+	}
+	if len(report.Files) == 0 {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("No supported source files found under %s", path)}
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": "# Project Security Report\n\n" + formatProjectReport(report),
+			},
+		},
+		"structuredContent": report,
+	}
+	return response, nil
+}
+
+// analyzeDirectory runs the analyze CLI command on a directory
+func analyzeDirectory(dir string) {
+	server := NewMCPServer()
+	fmt.Printf("Analyzing %s...\n\n", dir)
+
+	report, err := server.analyzeProject(context.Background(), dir, server.scans.workers)
+	if err != nil {
+		fmt.Printf("Analysis failed: %v\n", err)
+		os.Exit(1)
+	}
+	if len(report.Files) == 0 {
+		fmt.Println("Error: No supported source files found")
+		os.Exit(1)
+	}
+
+	fmt.Print("# Project Security Report\n\n")
+	fmt.Println(formatProjectReport(report))
+}
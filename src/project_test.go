@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestAnalyzeProject(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":1}]`)
+	workspace := newScanWorkspace(t, "src/A.java", "src/b/B.java", "src/gen/G.java", "src/Skip.java", "build/Out.java")
+	os.WriteFile(filepath.Join(workspace, ".gitignore"), []byte("build/\n"), 0o644)
+	os.WriteFile(filepath.Join(workspace, "src", ".aeyewireignore"), []byte("gen/\nSkip.java\n"), 0o644)
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_project",
+		"arguments": map[string]interface{}{},
+	})
+	if response.Error != nil {
+		t.Fatalf("analyze_project failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		StructuredContent projectReport `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	report := result.StructuredContent
+	if report.FilesAnalyzed != 2 || report.IssuesFound != 2 || report.HighCount != 2 {
+		t.Fatalf("unexpected project report: %s", jsonData)
+	}
+	if report.Files[0].FilePath != "src/A.java" || report.Files[1].FilePath != "src/b/B.java" {
+		t.Errorf("expected ignored files to be skipped and paths to be relative, got %+v", report.Files)
+	}
+	if !strings.Contains(result.Content[0].Text, "## Top Risk Files") {
+		t.Errorf("expected a top-risk section, got:\n%s", result.Content[0].Text)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "analyze_project",
+		"arguments": map[string]interface{}{"path": "src/A.java"},
+	})
+	if response.Error == nil {
+		t.Error("expected a file path to be rejected")
+	}
+}
+
+func TestBuildProjectReport(t *testing.T) {
+	result := func(severities ...models.SeverityLevel) *models.AnalysisResult {
+		analysis := &models.AnalysisResult{}
+		for _, severity := range severities {
+			analysis.Issues = append(analysis.Issues, models.SecurityIssue{Severity: severity})
+			switch severity {
+			case models.CRITICAL:
+				analysis.AnalysisMetadata.CriticalCount++
+			case models.LOW:
+				analysis.AnalysisMetadata.LowCount++
+			}
+		}
+		return analysis
+	}
+
+	report := buildProjectReport("/ws", []scanFileResult{
+		{FilePath: "/ws/low.py", Result: result(models.LOW, models.LOW, models.LOW)},
+		{FilePath: "/ws/clean.py", Result: result()},
+		{FilePath: "/ws/critical.py", Result: result(models.CRITICAL)},
+		{FilePath: "/ws/broken.py", Error: "unreadable"},
+	})
+
+	if report.FilesAnalyzed != 3 || report.FilesFailed != 1 || report.IssuesFound != 4 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	if len(report.TopRiskFiles) != 2 || report.TopRiskFiles[0].FilePath != "critical.py" || report.TopRiskFiles[1].RiskScore != 3 {
+		t.Errorf("unexpected top-risk files: %+v", report.TopRiskFiles)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// promptArgument describes an argument accepted by a prompt template
+type promptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+// promptDefinition describes a prompt template offered through prompts/list.
+// build renders the user message from the resolved language, the rule
+// checklist of its analyzer and the prompt arguments.
+type promptDefinition struct {
+	Name        string
+	Description string
+	Arguments   []promptArgument
+	build       func(language models.LanguageType, rules string, args map[string]string) string
+}
+
+// codeArguments are shared by every prompt template. The registered
+// languages are appended to the description of language by promptArguments.
+var codeArguments = []promptArgument{
+	{Name: "code", Description: "Source code to work on", Required: true},
+	{Name: "language", Description: "Programming language"},
+	{Name: "file_path", Description: "File path for context and language detection"},
+}
+
+// promptDefinitions lists the available prompt templates
+var promptDefinitions = []promptDefinition{
+	{
+		Name:        "security_review",
+		Description: "Security review of current file",
+		Arguments:   codeArguments,
+		build: func(language models.LanguageType, rules string, args map[string]string) string {
+			return fmt.Sprintf("Perform a security review of the following %s code%s.\n\n%s\n\n"+
+				"For each vulnerability found, give its severity, the affected line, why it is exploitable and how to fix it.\n\n%s",
+				language, fileContext(args), rules, codeBlock(args["code"]))
+		},
+	},
+	{
+		Name:        "explain_finding",
+		Description: "Explain this finding",
+		Arguments: append([]promptArgument{
+			{Name: "finding", Description: "Title or description of the security finding to explain", Required: true},
+		}, codeArguments...),
+		build: func(language models.LanguageType, rules string, args map[string]string) string {
+			return fmt.Sprintf("Explain the following security finding in the %s code below%s.\n\n"+
+				"Finding: %s\n\n"+
+				"Describe how an attacker could exploit it, with an example malicious input, which line makes the code vulnerable, "+
+				"and how to remediate it. For reference, these are the issues checked for %s code:\n\n%s\n\n%s",
+				language, fileContext(args), args["finding"], language, rules, codeBlock(args["code"]))
+		},
+	},
+	{
+		Name:        "secure_rewrite",
+		Description: "Write a secure version of this method",
+		Arguments:   codeArguments,
+		build: func(language models.LanguageType, rules string, args map[string]string) string {
+			return fmt.Sprintf("Rewrite the following %s code%s so that it is free of security vulnerabilities, "+
+				"keeping its behaviour and public signature unchanged.\n\n"+
+				"Make sure the rewritten code avoids every issue in this checklist:\n\n%s\n\n"+
+				"Return the complete rewritten code followed by a short list of the changes made.\n\n%s",
+				language, fileContext(args), rules, codeBlock(args["code"]))
+		},
+	},
+}
+
+// fileContext names the file a prompt refers to, if known
+func fileContext(args map[string]string) string {
+	if args["file_path"] == "" {
+		return ""
+	}
+	return fmt.Sprintf(" from %s", args["file_path"])
+}
+
+// codeBlock wraps code in a fenced block
+func codeBlock(code string) string {
+	return fmt.Sprintf("Code:\n```\n%s\n```", code)
+}
+
+// findPrompt returns the prompt template called name, or nil
+func findPrompt(name string) *promptDefinition {
+	for i := range promptDefinitions {
+		if promptDefinitions[i].Name == name {
+			return &promptDefinitions[i]
+		}
+	}
+	return nil
+}
+
+// promptArguments returns the arguments of prompt, listing the languages of
+// the registered analyzers in the description of the language argument
+func (s *MCPServer) promptArguments(prompt promptDefinition) []promptArgument {
+	arguments := append([]promptArgument{}, prompt.Arguments...)
+	for i := range arguments {
+		if arguments[i].Name == "language" {
+			arguments[i].Description = fmt.Sprintf("%s (%s)", arguments[i].Description, strings.Join(s.languageNames(), ", "))
+		}
+	}
+	return arguments
+}
+
+// handlePromptsList handles prompts/list request
+func (s *MCPServer) handlePromptsList(request *MCPRequest) (interface{}, *MCPError) {
+	prompts := []map[string]interface{}{}
+	for _, prompt := range promptDefinitions {
+		prompts = append(prompts, map[string]interface{}{
+			"name":        prompt.Name,
+			"description": prompt.Description,
+			"arguments":   s.promptArguments(prompt),
+		})
+	}
+
+	result := map[string]interface{}{
+		"prompts": prompts,
+	}
+	return result, nil
+}
+
+// handlePromptsGet handles prompts/get request
+func (s *MCPServer) handlePromptsGet(ctx context.Context, request *MCPRequest) (interface{}, *MCPError) {
+	name, ok := request.Params["name"].(string)
+	if !ok {
+		return nil, &MCPError{Code: -32602, Message: "Invalid prompt name"}
+	}
+
+	prompt := findPrompt(name)
+	if prompt == nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unknown prompt: %s", name)}
+	}
+
+	args := map[string]string{}
+	rawArgs, _ := request.Params["arguments"].(map[string]interface{})
+	for key, value := range rawArgs {
+		if str, ok := value.(string); ok {
+			args[key] = str
+		}
+	}
+
+	for _, argument := range prompt.Arguments {
+		if argument.Required && args[argument.Name] == "" {
+			return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Missing required argument: %s", argument.Name)}
+		}
+	}
+
+	language, analyzer, mcpErr := s.resolveAnalyzer(ctx, args["language"], args["code"], args["file_path"])
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	text := prompt.build(language, analyzers.FormatRules(analyzer.GetRules()), args)
+
+	result := map[string]interface{}{
+		"description": prompt.Description,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": map[string]interface{}{
+					"type": "text",
+					"text": text,
+				},
+			},
+		},
+	}
+	return result, nil
+}
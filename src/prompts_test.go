@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptsGet(t *testing.T) {
+	server := NewMCPServer()
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		contains  string
+		errorCode int
+	}{
+		{
+			name:     "Security review with explicit language",
+			params:   map[string]interface{}{"name": "security_review", "arguments": map[string]interface{}{"code": "class A {}", "language": "java"}},
+			contains: "SQL Injection",
+		},
+		{
+			name:     "Explain finding with detected language",
+			params:   map[string]interface{}{"name": "explain_finding", "arguments": map[string]interface{}{"code": "class A {}", "file_path": "A.cs", "finding": "Hardcoded password"}},
+			contains: "Finding: Hardcoded password",
+		},
+		{
+			name:      "Missing required argument",
+			params:    map[string]interface{}{"name": "explain_finding", "arguments": map[string]interface{}{"code": "class A {}", "language": "java"}},
+			errorCode: -32602,
+		},
+		{
+			name:      "Unknown prompt",
+			params:    map[string]interface{}{"name": "no_such_prompt"},
+			errorCode: -32602,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := callMethod(t, server, "prompts/get", tt.params)
+			if tt.errorCode != 0 {
+				if response.Error == nil || response.Error.Code != tt.errorCode {
+					t.Errorf("expected error %d, got %+v", tt.errorCode, response)
+				}
+				return
+			}
+			if response.Error != nil {
+				t.Fatalf("prompts/get failed: %s", response.Error.Message)
+			}
+
+			messages := response.Result.(map[string]interface{})["messages"].([]map[string]interface{})
+			text := messages[0]["content"].(map[string]interface{})["text"].(string)
+			if !strings.Contains(text, tt.contains) {
+				t.Errorf("expected prompt to contain %q, got:\n%s", tt.contains, text)
+			}
+			if strings.Contains(text, "Return findings as") {
+				t.Error("prompt should not include the JSON output instructions")
+			}
+		})
+	}
+}
+
+func TestPromptsListLanguages(t *testing.T) {
+	server := NewMCPServer()
+	delete(server.analyzers, "java")
+
+	response := callMethod(t, server, "prompts/list", nil)
+	prompts := response.Result.(map[string]interface{})["prompts"].([]map[string]interface{})
+	for _, prompt := range prompts {
+		for _, argument := range prompt["arguments"].([]promptArgument) {
+			if argument.Name != "language" {
+				continue
+			}
+			if argument.Description != "Programming language (csharp, react_javascript, react_typescript, auto)" {
+				t.Errorf("%s: unexpected language description %q", prompt["name"], argument.Description)
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// MAX_STORED_REPORTS bounds how many past reports are kept in memory for
+// each session
+const MAX_STORED_REPORTS = 100
+
+// storedReport is a completed analysis kept for the resources capability
+type storedReport struct {
+	ID string
+	// SessionID is the session that produced the report; only that session
+	// can see it
+	SessionID string
+	FilePath  string
+	// SourcePath is the resolved path of the analyzed workspace file, or ""
+	// when the code did not come from a known file
+	SourcePath string
+	CreatedAt  time.Time
+	Result     *models.AnalysisResult
+	Markdown   string
+}
+
+// reportStore keeps the most recent analysis reports of each session,
+// oldest first
+type reportStore struct {
+	mu       sync.RWMutex
+	reports  map[string]*storedReport
+	order    []string
+	capacity int
+}
+
+// newReportStore creates a store holding at most capacity reports per
+// session
+func newReportStore(capacity int) *reportStore {
+	return &reportStore{
+		reports:  make(map[string]*storedReport),
+		capacity: capacity,
+	}
+}
+
+// add stores a report of a session under a new ID, evicting the session's
+// oldest when it has capacity reports
+func (rs *reportStore) add(sessionID string, filePath string, result *models.AnalysisResult, markdown string) *storedReport {
+	return rs.addFile(sessionID, "", filePath, result, markdown)
+}
+
+// addFile stores a report for the workspace file at sourcePath, so it can be
+// found by latestForFile
+func (rs *reportStore) addFile(sessionID string, sourcePath string, filePath string, result *models.AnalysisResult, markdown string) *storedReport {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	report := &storedReport{
+		ID:         hex.EncodeToString(idBytes),
+		SessionID:  sessionID,
+		FilePath:   filePath,
+		SourcePath: sourcePath,
+		CreatedAt:  time.Now(),
+		Result:     result,
+		Markdown:   markdown,
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.reports[report.ID] = report
+	rs.order = append(rs.order, report.ID)
+
+	kept := 0
+	for i := len(rs.order) - 1; i >= 0; i-- {
+		if rs.reports[rs.order[i]].SessionID != sessionID {
+			continue
+		}
+		if kept++; kept > rs.capacity {
+			delete(rs.reports, rs.order[i])
+			rs.order = append(rs.order[:i], rs.order[i+1:]...)
+		}
+	}
+
+	return report
+}
+
+// get returns the session's report with id, or nil if it is unknown,
+// evicted or belongs to another session
+func (rs *reportStore) get(sessionID string, id string) *storedReport {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	if report := rs.reports[id]; report != nil && report.SessionID == sessionID {
+		return report
+	}
+	return nil
+}
+
+// list returns the session's stored reports, newest first
+func (rs *reportStore) list(sessionID string) []*storedReport {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	reports := []*storedReport{}
+	for i := len(rs.order) - 1; i >= 0; i-- {
+		if report := rs.reports[rs.order[i]]; report.SessionID == sessionID {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+// latestForFile returns the session's newest report for the workspace file
+// at sourcePath, or nil if there is none
+func (rs *reportStore) latestForFile(sessionID string, sourcePath string) *storedReport {
+	for _, report := range rs.list(sessionID) {
+		if report.SourcePath == sourcePath {
+			return report
+		}
+	}
+	return nil
+}
+
+// findIssue returns the session's newest stored issue with fingerprint, and
+// the report holding it, or nil if none of its reports has it
+func (rs *reportStore) findIssue(sessionID string, fingerprint string) (*storedReport, *models.SecurityIssue) {
+	for _, report := range rs.list(sessionID) {
+		for j := range report.Result.Issues {
+			if report.Result.Issues[j].Fingerprint == fingerprint {
+				return report, &report.Result.Issues[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// forget drops every report of a session that has ended
+func (rs *reportStore) forget(sessionID string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	order := []string{}
+	for _, id := range rs.order {
+		if rs.reports[id].SessionID == sessionID {
+			delete(rs.reports, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	rs.order = order
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+const (
+	// REPORT_URI_PREFIX addresses stored analysis reports by ID
+	REPORT_URI_PREFIX = "aeyewire://reports/"
+
+	// RULES_URI_PREFIX addresses the security rule catalog of each language
+	RULES_URI_PREFIX = "aeyewire://rules/"
+)
+
+// reportURI returns the resource URI of a stored report
+func reportURI(id string) string {
+	return REPORT_URI_PREFIX + id
+}
+
+// reportName returns a human-readable name for a stored report
+func reportName(report *storedReport) string {
+	if report.FilePath != "" {
+		return fmt.Sprintf("Security report for %s", report.FilePath)
+	}
+	return fmt.Sprintf("Security report %s (%s)", report.ID, report.Result.Language)
+}
+
+// sortedLanguages returns the languages with a registered analyzer in a
+// stable order
+func (s *MCPServer) sortedLanguages() []models.LanguageType {
+	languages := make([]models.LanguageType, 0, len(s.analyzers))
+	for language := range s.analyzers {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool { return languages[i] < languages[j] })
+	return languages
+}
+
+// registeredLanguageNames returns the names of the registered languages,
+// sorted
+func (s *MCPServer) registeredLanguageNames() []string {
+	names := []string{}
+	for _, language := range s.sortedLanguages() {
+		names = append(names, string(language))
+	}
+	return names
+}
+
+// languageNames returns the values accepted by language arguments: the
+// registered languages followed by "auto"
+func (s *MCPServer) languageNames() []string {
+	return append(s.registeredLanguageNames(), "auto")
+}
+
+// handleResourcesList handles resources/list request
+func (s *MCPServer) handleResourcesList(sess *session, request *MCPRequest) (interface{}, *MCPError) {
+	resources := []map[string]interface{}{}
+
+	for _, report := range s.reports.list(sess.id) {
+		resources = append(resources, map[string]interface{}{
+			"uri":         reportURI(report.ID),
+			"name":        reportName(report),
+			"description": report.Result.Summary,
+			"mimeType":    "text/markdown",
+		})
+	}
+
+	for _, language := range s.sortedLanguages() {
+		resources = append(resources, map[string]interface{}{
+			"uri":         RULES_URI_PREFIX + string(language),
+			"name":        fmt.Sprintf("Security rules for %s", language),
+			"description": fmt.Sprintf("Security rule catalog checked by the %s analyzer", language),
+			"mimeType":    "text/plain",
+		})
+	}
+
+	result := map[string]interface{}{
+		"resources": resources,
+	}
+	return result, nil
+}
+
+// handleResourceTemplatesList handles resources/templates/list request
+func (s *MCPServer) handleResourceTemplatesList(request *MCPRequest) (interface{}, *MCPError) {
+	templates := []map[string]interface{}{
+		{
+			"uriTemplate": REPORT_URI_PREFIX + "{id}",
+			"name":        "Security analysis report",
+			"description": "A past analysis report, as markdown and as JSON",
+			"mimeType":    "text/markdown",
+		},
+		{
+			"uriTemplate": FILE_REPORT_URI_PREFIX + "{path}",
+			"name":        "Latest security report of a file",
+			"description": "The newest report for a workspace file, analyzed on first read; subscribe to be notified when the file changes and is re-analyzed",
+			"mimeType":    "text/markdown",
+		},
+		{
+			"uriTemplate": RULES_URI_PREFIX + "{language}",
+			"name":        "Security rule catalog",
+			"description": "The security rules an analyzer checks for",
+			"mimeType":    "text/plain",
+		},
+	}
+
+	result := map[string]interface{}{
+		"resourceTemplates": templates,
+	}
+	return result, nil
+}
+
+// reportContents returns a report both as markdown and as the JSON
+// AnalysisResult
+func reportContents(uri string, report *storedReport) []map[string]interface{} {
+	jsonData, _ := json.MarshalIndent(report.Result, "", "  ")
+	return []map[string]interface{}{
+		{
+			"uri":      uri,
+			"mimeType": "text/markdown",
+			"text":     report.Markdown,
+		},
+		{
+			"uri":      uri,
+			"mimeType": "application/json",
+			"text":     string(jsonData),
+		},
+	}
+}
+
+// handleResourcesRead handles resources/read request. Reports are returned
+// both as markdown and as the JSON AnalysisResult.
+func (s *MCPServer) handleResourcesRead(ctx context.Context, request *MCPRequest) (interface{}, *MCPError) {
+	uri, ok := request.Params["uri"].(string)
+	if !ok || uri == "" {
+		return nil, &MCPError{Code: -32602, Message: "Missing or invalid 'uri' parameter"}
+	}
+
+	var contents []map[string]interface{}
+
+	switch {
+	case strings.HasPrefix(uri, REPORT_URI_PREFIX):
+		report := s.reports.get(sessionFrom(ctx).id, strings.TrimPrefix(uri, REPORT_URI_PREFIX))
+		if report == nil {
+			return nil, &MCPError{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+		}
+
+		contents = reportContents(uri, report)
+
+	case strings.HasPrefix(uri, FILE_REPORT_URI_PREFIX):
+		path, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), filePathFromURI(uri))
+		if mcpErr != nil {
+			return nil, mcpErr
+		}
+
+		report := s.reports.latestForFile(sessionFrom(ctx).id, path)
+		if report == nil {
+			if _, err := os.Stat(path); err != nil {
+				return nil, &MCPError{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+			}
+
+			var err error
+			if report, err = s.analyzeWorkspaceFile(ctx, path); err != nil {
+				return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Analysis failed: %v", err)}
+			}
+		}
+		contents = reportContents(uri, report)
+
+	case strings.HasPrefix(uri, RULES_URI_PREFIX):
+		language := models.LanguageType(strings.TrimPrefix(uri, RULES_URI_PREFIX))
+		analyzer, ok := s.analyzers[language]
+		if !ok {
+			return nil, &MCPError{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+		}
+
+		contents = []map[string]interface{}{
+			{
+				"uri":      uri,
+				"mimeType": "text/plain",
+				"text":     analyzer.GetSecurityRulesPrompt(),
+			},
+		}
+
+	default:
+		return nil, &MCPError{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+	}
+
+	result := map[string]interface{}{
+		"contents": contents,
+	}
+	return result, nil
+}
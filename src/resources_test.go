@@ -0,0 +1,106 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestReportStoreEviction(t *testing.T) {
+	store := newReportStore(2)
+	result := &models.AnalysisResult{Language: models.JAVA}
+
+	first := store.add("one", "A.java", result, "# A")
+	other := store.add("two", "X.java", result, "# X")
+	second := store.add("one", "B.java", result, "# B")
+	third := store.add("one", "C.java", result, "# C")
+
+	if store.get("one", first.ID) != nil {
+		t.Error("expected the oldest report to be evicted")
+	}
+	if store.get("two", other.ID) != other {
+		t.Error("expected another session's report to be kept")
+	}
+
+	reports := store.list("one")
+	if len(reports) != 2 || reports[0] != third || reports[1] != second {
+		t.Errorf("expected newest-first [C B], got %d reports", len(reports))
+	}
+}
+
+func TestReportStoreSessions(t *testing.T) {
+	store := newReportStore(10)
+	result := &models.AnalysisResult{Language: models.JAVA, Issues: []models.SecurityIssue{{Title: "XSS", Fingerprint: "f1"}}}
+	report := store.add("one", "A.java", result, "# A")
+
+	if store.get("two", report.ID) != nil || len(store.list("two")) != 0 {
+		t.Error("expected a report to be hidden from other sessions")
+	}
+	if found, _ := store.findIssue("two", "f1"); found != nil {
+		t.Error("expected an issue to be hidden from other sessions")
+	}
+	if found, _ := store.findIssue("one", "f1"); found != report {
+		t.Error("expected the session to find its own issue")
+	}
+
+	store.forget("one")
+	if store.get("one", report.ID) != nil {
+		t.Error("expected the reports of a forgotten session to be dropped")
+	}
+}
+
+func TestResourcesReadReport(t *testing.T) {
+	server := NewMCPServer()
+	report := server.reports.add("test", "A.java", &models.AnalysisResult{Language: models.JAVA, Summary: "No security issues detected."}, "# Report")
+
+	response := callMethod(t, server, "resources/read", map[string]interface{}{"uri": reportURI(report.ID)})
+	if response.Error != nil {
+		t.Fatalf("resources/read failed: %s", response.Error.Message)
+	}
+
+	contents := response.Result.(map[string]interface{})["contents"].([]map[string]interface{})
+	if len(contents) != 2 || contents[0]["mimeType"] != "text/markdown" || contents[1]["mimeType"] != "application/json" {
+		t.Errorf("expected markdown and JSON contents, got %v", contents)
+	}
+}
+
+func TestResourcesReadRules(t *testing.T) {
+	server := NewMCPServer()
+
+	tests := []struct {
+		name  string
+		uri   string
+		found bool
+	}{
+		{"Java rules", RULES_URI_PREFIX + "java", true},
+		{"C# rules", RULES_URI_PREFIX + "csharp", true},
+		{"Unknown language", RULES_URI_PREFIX + "cobol", false},
+		{"Unknown report", REPORT_URI_PREFIX + "missing", false},
+		{"Foreign scheme", "file:///etc/passwd", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := callMethod(t, server, "resources/read", map[string]interface{}{"uri": tt.uri})
+			if tt.found && response.Error != nil {
+				t.Errorf("expected %s to be readable, got %s", tt.uri, response.Error.Message)
+			}
+			if !tt.found && (response.Error == nil || response.Error.Code != -32002) {
+				t.Errorf("expected resource not found for %s, got %+v", tt.uri, response)
+			}
+		})
+	}
+}
+
+func TestResourcesList(t *testing.T) {
+	server := NewMCPServer()
+	server.reports.add("test", "A.java", &models.AnalysisResult{Language: models.JAVA}, "# Report")
+
+	response := callMethod(t, server, "resources/list", nil)
+	resources := response.Result.(map[string]interface{})["resources"].([]map[string]interface{})
+
+	// One report plus one rule catalog per registered analyzer
+	if len(resources) != 1+len(server.analyzers) {
+		t.Errorf("expected %d resources, got %d", 1+len(server.analyzers), len(resources))
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ROOTS_TIMEOUT bounds how long we wait for the client to answer roots/list
+const ROOTS_TIMEOUT = 10 * time.Second
+
+// rootsResult is the result of a roots/list request
+type rootsResult struct {
+	Roots []struct {
+		URI  string `json:"uri"`
+		Name string `json:"name"`
+	} `json:"roots"`
+}
+
+// refreshRoots fetches the client's roots in the background. It is called
+// once the client is initialized and whenever its roots change; failures
+// leave the roots unset so the next path check asks again. An HTTP client
+// that has not opened its event stream yet could not receive the request,
+// so the fetch is left to that path check.
+func (s *MCPServer) refreshRoots(sess *session) {
+	if sess.remote || !sess.streaming() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ROOTS_TIMEOUT)
+	defer cancel()
+
+	if _, err := s.workspaceRoots(withNotifier(ctx, sess.notify), sess); err != nil {
+		fmt.Fprintf(os.Stderr, "Error fetching roots: %v\n", err)
+	}
+}
+
+// workspaceRoots returns the resolved directories path-based operations are
+// confined to. Remote sessions only get the directory the server was
+// configured with. Clients that declare the roots capability are asked
+// through roots/list; for other clients the server's working directory is
+// the only root.
+func (s *MCPServer) workspaceRoots(ctx context.Context, sess *session) ([]string, error) {
+	if sess.remote {
+		if sess.serverRoot == "" {
+			return nil, fmt.Errorf("file access is disabled over HTTP; start the server with MCP_HTTP_ROOT set to allow it")
+		}
+		return []string{sess.serverRoot}, nil
+	}
+
+	if !sess.clientSupports("roots") {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return []string{resolveExisting(cwd)}, nil
+	}
+
+	if roots, _, ok := sess.cachedRoots(); ok {
+		return roots, nil
+	}
+
+	// Only one fetch runs at a time; callers that waited for it reuse its
+	// result
+	sess.rootsFetchMu.Lock()
+	defer sess.rootsFetchMu.Unlock()
+
+	roots, generation, ok := sess.cachedRoots()
+	if ok {
+		return roots, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ROOTS_TIMEOUT)
+	defer cancel()
+
+	raw, err := sess.request(ctx, notifierFrom(ctx), "roots/list", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result rootsResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("invalid roots/list result: %w", err)
+	}
+
+	roots = []string{}
+	for _, root := range result.Roots {
+		rootURL, err := url.Parse(root.URI)
+		if err != nil || rootURL.Scheme != "file" {
+			continue
+		}
+		roots = append(roots, resolveExisting(filepath.FromSlash(rootURL.Path)))
+	}
+
+	sess.setRoots(roots, generation)
+	return roots, nil
+}
+
+// workspacePath resolves path against the session's roots and rejects it if
+// it falls outside all of them. Relative paths are taken relative to the
+// first root.
+func (s *MCPServer) workspacePath(ctx context.Context, sess *session, path string) (string, *MCPError) {
+	roots, err := s.workspaceRoots(ctx, sess)
+	if err != nil && sess.remote {
+		return "", &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid path: %v", err)}
+	}
+	if err != nil {
+		return "", &MCPError{Code: -32603, Message: fmt.Sprintf("Could not determine workspace roots: %v", err)}
+	}
+
+	resolved, err := resolveInRoots(roots, path)
+	if err != nil {
+		return "", &MCPError{Code: -32602, Message: fmt.Sprintf("Invalid path: %v", err)}
+	}
+	return resolved, nil
+}
+
+// resolveInRoots returns the absolute, symlink-free form of path if it lies
+// inside one of roots
+func resolveInRoots(roots []string, path string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("%s: the client declared no workspace roots", path)
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(roots[0], path)
+	}
+	resolved := resolveExisting(path)
+
+	for _, root := range roots {
+		if withinRoot(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the workspace roots", path)
+}
+
+// resolveExisting cleans path and resolves the symlinks of its longest
+// existing prefix, so paths to files that do not exist yet still resolve
+func resolveExisting(path string) string {
+	path = filepath.Clean(path)
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(resolveExisting(parent), filepath.Base(path))
+}
+
+// withinRoot reports whether path is root or lies below it
+func withinRoot(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cachedRoots returns the roots last fetched for the session, and the
+// generation to pass to setRoots when they need fetching
+func (sess *session) cachedRoots() ([]string, int, bool) {
+	sess.rootsMu.Lock()
+	defer sess.rootsMu.Unlock()
+	return sess.roots, sess.rootsGeneration, sess.rootsValid
+}
+
+// setRoots records the session's resolved roots, unless they changed again
+// since generation was read
+func (sess *session) setRoots(roots []string, generation int) {
+	sess.rootsMu.Lock()
+	defer sess.rootsMu.Unlock()
+	if sess.rootsGeneration == generation {
+		sess.roots = roots
+		sess.rootsValid = true
+	}
+}
+
+// invalidateRoots forgets the session's roots after the client changed them
+func (sess *session) invalidateRoots() {
+	sess.rootsMu.Lock()
+	defer sess.rootsMu.Unlock()
+	sess.roots = nil
+	sess.rootsValid = false
+	sess.rootsGeneration++
+}
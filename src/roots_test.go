@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveInRoots(t *testing.T) {
+	workspace := resolveExisting(t.TempDir())
+	outside := resolveExisting(t.TempDir())
+	os.MkdirAll(filepath.Join(workspace, "src"), 0o755)
+	if err := os.Symlink(outside, filepath.Join(workspace, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{filepath.Join(workspace, "src", "A.java"), true},
+		{"src/A.java", true},
+		{workspace, true},
+		{filepath.Join(workspace, "src", "..", "..", "etc", "passwd"), false},
+		{"../A.java", false},
+		{filepath.Join(workspace, "escape", "A.java"), false},
+		{filepath.Join(outside, "A.java"), false},
+		{workspace + "-other/A.java", false},
+	}
+
+	for _, tt := range tests {
+		_, err := resolveInRoots([]string{workspace}, tt.path)
+		if (err == nil) != tt.allowed {
+			t.Errorf("resolveInRoots(%q) error = %v, allowed %v", tt.path, err, tt.allowed)
+		}
+	}
+
+	if _, err := resolveInRoots(nil, "A.java"); err == nil {
+		t.Error("expected every path to be rejected without roots")
+	}
+}
+
+func TestRootsListAndChange(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	first := resolveExisting(t.TempDir())
+	second := resolveExisting(t.TempDir())
+
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{"roots":{"listChanged":true}}`)
+
+	// answerRoots answers the roots/list request the server sends next
+	answerRoots := func(root string) {
+		request := client.nextIgnoringLogs()
+		if string(request["method"]) != `"roots/list"` {
+			t.Fatalf("expected a roots/list request, got %v", request)
+		}
+		client.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"roots":[{"uri":"file://%s","name":"workspace"}]}}`, request["id"], filepath.ToSlash(root)))
+	}
+	answerRoots(first)
+
+	for _, root := range []string{first, second} {
+		os.WriteFile(filepath.Join(root, "A.java"), []byte("public class A {}"), 0o644)
+	}
+
+	// analyze sends analyze_file for path and returns the error, if any
+	analyze := func(id int, path string) *MCPError {
+		client.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"analyze_file","arguments":{"path":%q,"language":"java"}}}`, id, path))
+		var response MCPResponse
+		message, _ := json.Marshal(client.nextIgnoringLogs())
+		json.Unmarshal(message, &response)
+		return response.Error
+	}
+
+	if err := analyze(1, filepath.Join(first, "A.java")); err != nil {
+		t.Errorf("expected a path inside the root to be accepted, got %v", err.Message)
+	}
+	if err := analyze(2, filepath.Join(second, "A.java")); err == nil || !strings.Contains(err.Message, "outside the workspace roots") {
+		t.Errorf("expected a path outside the root to be rejected, got %+v", err)
+	}
+
+	client.send(`{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}`)
+	answerRoots(second)
+
+	if err := analyze(3, filepath.Join(second, "A.java")); err != nil {
+		t.Errorf("expected the new root to be used after list_changed, got %v", err.Message)
+	}
+}
+
+func TestAnalyzeSecurityFilePathLabel(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	server := NewMCPServer()
+
+	tests := []struct {
+		filePath string
+		linked   bool
+	}{
+		{"roots.go", true},
+		{filepath.Join(t.TempDir(), "A.java"), false},
+	}
+
+	for _, tt := range tests {
+		args := map[string]interface{}{"code": "public class A {}", "language": "java", "file_path": tt.filePath}
+		if _, err := server.handleAnalyzeSecurity(context.Background(), args); err != nil {
+			t.Fatalf("file_path %q was rejected: %v", tt.filePath, err.Message)
+		}
+
+		report := server.reports.list("")[0]
+		if report.FilePath != tt.filePath || (report.SourcePath != "") != tt.linked {
+			t.Errorf("file_path %q stored as %q with source %q, linked %v", tt.filePath, report.FilePath, report.SourcePath, tt.linked)
+		}
+	}
+}
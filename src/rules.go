@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// ruleEntry is a rule along with the languages whose analyzer checks it
+type ruleEntry struct {
+	models.SecurityRule
+	Languages []models.LanguageType `json:"languages"`
+}
+
+// rules returns the rules of every analyzer, or of the analyzer for
+// language when it is set, merging rules shared by several languages
+func (s *MCPServer) rules(language models.LanguageType) []ruleEntry {
+	entries := []ruleEntry{}
+	index := map[string]int{}
+	for _, lang := range s.sortedLanguages() {
+		if language != "" && lang != language {
+			continue
+		}
+		for _, rule := range s.analyzers[lang].GetRules() {
+			if i, ok := index[rule.ID]; ok {
+				entries[i].Languages = append(entries[i].Languages, lang)
+				continue
+			}
+			index[rule.ID] = len(entries)
+			entries = append(entries, ruleEntry{SecurityRule: rule, Languages: []models.LanguageType{lang}})
+		}
+	}
+	return entries
+}
+
+// findRule returns the rule with the given ID, or nil
+func (s *MCPServer) findRule(id string) *ruleEntry {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, entry := range s.rules("") {
+		if entry.ID == id {
+			return &entry
+		}
+	}
+	return nil
+}
+
+// handleListRules handles the list_rules tool
+func (s *MCPServer) handleListRules(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	languageStr, _ := args["language"].(string)
+
+	language := models.LanguageType(languageStr)
+	if _, ok := s.analyzers[language]; languageStr != "" && !ok {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unsupported language: %s", languageStr)}
+	}
+	entries := s.rules(language)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Security Rules\n\n%d rule(s).\n\n", len(entries)))
+	sb.WriteString("| Rule | Title | Severity | CWE | OWASP |\n|---|---|---|---|---|\n")
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+			entry.ID, entry.Title, entry.Severity, strings.Join(entry.CWE, ", "), entry.OWASP))
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": sb.String(),
+			},
+		},
+		"structuredContent": map[string]interface{}{
+			"rules": entries,
+		},
+	}
+	return response, nil
+}
+
+// handleGetRule handles the get_rule tool
+func (s *MCPServer) handleGetRule(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	id, _ := args["rule_id"].(string)
+
+	entry := s.findRule(id)
+	if entry == nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unknown rule: %s; list_rules returns the available rules", id)}
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatRule(entry),
+			},
+		},
+		"structuredContent": entry,
+	}
+	return response, nil
+}
+
+// formatRule formats a rule as markdown
+func formatRule(entry *ruleEntry) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# %s\n\n", entry.Title))
+	sb.WriteString(fmt.Sprintf("**Rule**: %s\n\n", entry.ID))
+	sb.WriteString(fmt.Sprintf("**Category**: %s\n\n", entry.Category))
+	sb.WriteString(fmt.Sprintf("**Default Severity**: %s\n\n", entry.Severity))
+	if len(entry.CWE) > 0 {
+		sb.WriteString(fmt.Sprintf("**CWE**: %s\n\n", strings.Join(entry.CWE, ", ")))
+	}
+	if entry.OWASP != "" {
+		sb.WriteString(fmt.Sprintf("**OWASP**: %s\n\n", entry.OWASP))
+	}
+
+	languages := []string{}
+	for _, language := range entry.Languages {
+		languages = append(languages, string(language))
+	}
+	sb.WriteString(fmt.Sprintf("**Languages**: %s\n\n", strings.Join(languages, ", ")))
+	sb.WriteString(fmt.Sprintf("**Checks for**: %s\n", entry.Prompt))
+
+	return sb.String()
+}
+
+// rulesCommand runs the rules CLI command: it lists the rules of every
+// analyzer, or of one language, or describes a single rule
+func rulesCommand(args []string) {
+	server := NewMCPServer()
+
+	language := models.LanguageType("")
+	if len(args) > 0 {
+		if entry := server.findRule(args[0]); entry != nil {
+			fmt.Print(formatRule(entry))
+			return
+		}
+		language = models.LanguageType(args[0])
+		if _, ok := server.analyzers[language]; !ok {
+			fmt.Printf("Error: %s is neither a rule ID nor a supported language\n", args[0])
+			os.Exit(1)
+		}
+	}
+
+	for _, entry := range server.rules(language) {
+		fmt.Printf("%-36s %-9s %-12s %s\n", entry.ID, entry.Severity, strings.Join(entry.CWE, ","), entry.Title)
+	}
+}
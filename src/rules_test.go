@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestListRules(t *testing.T) {
+	server := NewMCPServer()
+
+	list := func(arguments map[string]interface{}) map[string][]string {
+		t.Helper()
+		response := callMethod(t, server, "tools/call", map[string]interface{}{"name": "list_rules", "arguments": arguments})
+		if response.Error != nil {
+			t.Fatalf("list_rules failed: %s", response.Error.Message)
+		}
+
+		jsonData, _ := json.Marshal(response.Result)
+		var result struct {
+			StructuredContent struct {
+				Rules []struct {
+					ID        string   `json:"id"`
+					Languages []string `json:"languages"`
+				} `json:"rules"`
+			} `json:"structuredContent"`
+		}
+		json.Unmarshal(jsonData, &result)
+
+		languages := map[string][]string{}
+		for _, rule := range result.StructuredContent.Rules {
+			languages[rule.ID] = rule.Languages
+		}
+		return languages
+	}
+
+	all := list(map[string]interface{}{})
+	if strings.Join(all["react/dangerous-html"], ",") != "react_javascript,react_typescript" {
+		t.Errorf("expected shared React rules to be listed once for both languages, got %v", all["react/dangerous-html"])
+	}
+	if strings.Join(all["react/ts-null-check"], ",") != "react_typescript" {
+		t.Errorf("expected TypeScript rules for TypeScript only, got %v", all["react/ts-null-check"])
+	}
+
+	java := list(map[string]interface{}{"language": "java"})
+	if len(java) != 25 || java["csharp/sql-injection"] != nil {
+		t.Errorf("expected the 25 Java rules only, got %d rules", len(java))
+	}
+
+	tool, _ := server.tools.Get("list_rules")
+	language := tool.InputSchema["properties"].(map[string]interface{})["language"].(map[string]interface{})
+	if enum := strings.Join(language["enum"].([]string), ","); enum != strings.Join(server.registeredLanguageNames(), ",") || strings.Contains(enum, "auto") {
+		t.Errorf("expected the language enum to list every registered language and no auto, got %s", enum)
+	}
+}
+
+func TestGetRule(t *testing.T) {
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "get_rule",
+		"arguments": map[string]interface{}{"rule_id": "Java/SQL-Injection"},
+	})
+	if response.Error != nil {
+		t.Fatalf("get_rule failed: %s", response.Error.Message)
+	}
+	jsonData, _ := json.Marshal(response.Result)
+	if !strings.Contains(string(jsonData), `"cwe":["CWE-89"]`) || !strings.Contains(string(jsonData), "A03:2021-Injection") {
+		t.Errorf("unexpected rule: %s", jsonData)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "get_rule",
+		"arguments": map[string]interface{}{"rule_id": "java/nonexistent"},
+	})
+	if response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("expected an unknown rule to be rejected, got %+v", response)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+// SAMPLING_MAX_TOKENS bounds the reply requested from the client's model
+const SAMPLING_MAX_TOKENS = 8192
+
+// samplingResult is the part of a sampling/createMessage result we use
+type samplingResult struct {
+	Model   string `json:"model"`
+	Content struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// samplingFallback returns a Sampler that sends the LLM messages to the
+// client's model through sampling/createMessage. It is used when LMStudio is
+// unavailable and the client declared the sampling capability.
+func (s *MCPServer) samplingFallback(sess *session) services.Sampler {
+	return func(ctx context.Context, messages []services.Message) (string, error) {
+		// Sampling only carries user and assistant turns; the system message
+		// becomes the system prompt
+		systemPrompt := ""
+		samplingMessages := []map[string]interface{}{}
+		for _, message := range messages {
+			if message.Role == "system" {
+				systemPrompt += message.Content
+				continue
+			}
+			samplingMessages = append(samplingMessages, map[string]interface{}{
+				"role": message.Role,
+				"content": map[string]interface{}{
+					"type": "text",
+					"text": message.Content,
+				},
+			})
+		}
+
+		params := map[string]interface{}{
+			"messages":       samplingMessages,
+			"systemPrompt":   systemPrompt,
+			"maxTokens":      SAMPLING_MAX_TOKENS,
+			"temperature":    0.1,
+			"includeContext": "none",
+			"modelPreferences": map[string]interface{}{
+				"hints":                []map[string]string{{"name": s.llmService.Model()}},
+				"intelligencePriority": 0.8,
+			},
+		}
+
+		raw, err := sess.request(ctx, notifierFrom(ctx), "sampling/createMessage", params)
+		if err != nil {
+			return "", err
+		}
+
+		var result samplingResult
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return "", fmt.Errorf("invalid sampling result: %w", err)
+		}
+		if result.Content.Type != "text" {
+			return "", fmt.Errorf("sampling returned %s content, expected text", result.Content.Type)
+		}
+
+		return result.Content.Text, nil
+	}
+}
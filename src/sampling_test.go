@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+func TestSamplingFallback(t *testing.T) {
+	// Point the LLM service at a server that is no longer listening
+	unavailable := httptest.NewServer(nil)
+	unavailable.Close()
+	t.Setenv("LMSTUDIO_BASE_URL", unavailable.URL)
+
+	client := startStdio(t, NewMCPServer())
+	client.initialize(`{"sampling":{}}`)
+
+	client.send(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"analyze_security","arguments":{"code":"public class A {}","language":"java"}}}`)
+
+	request := client.nextIgnoringLogs()
+	var method string
+	json.Unmarshal(request["method"], &method)
+	if method != "sampling/createMessage" {
+		t.Fatalf("expected a sampling request, got %s", method)
+	}
+
+	var params struct {
+		SystemPrompt string `json:"systemPrompt"`
+		Messages     []struct {
+			Role string `json:"role"`
+		} `json:"messages"`
+	}
+	json.Unmarshal(request["params"], &params)
+	if params.SystemPrompt == "" || len(params.Messages) != 1 || params.Messages[0].Role != "user" {
+		t.Errorf("unexpected sampling params: %s", request["params"])
+	}
+
+	client.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"role":"assistant","model":"host-model","content":{"type":"text","text":"[{\"title\":\"XXE\",\"severity\":\"HIGH\"}]"}}}`, request["id"]))
+
+	var result struct {
+		StructuredContent models.AnalysisResult `json:"structuredContent"`
+	}
+	json.Unmarshal(client.nextIgnoringLogs()["result"], &result)
+
+	metadata := result.StructuredContent.AnalysisMetadata
+	if metadata.Provider != services.PROVIDER_SAMPLING || metadata.IssuesFound != 1 {
+		t.Errorf("expected one issue from the sampling provider, got %+v", metadata)
+	}
+}
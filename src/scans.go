@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+const (
+	// DEFAULT_SCAN_WORKERS is the number of files analyzed in parallel by
+	// scan jobs when MCP_SCAN_WORKERS is not set
+	DEFAULT_SCAN_WORKERS = 2
+
+	// MAX_SCAN_JOBS bounds how many jobs are kept for each session; the
+	// oldest finished jobs are forgotten first
+	MAX_SCAN_JOBS = 20
+)
+
+// Scan job states
+const (
+	SCAN_RUNNING   = "running"
+	SCAN_COMPLETED = "completed"
+	SCAN_CANCELLED = "cancelled"
+)
+
+// scanFileResult is the outcome of analyzing one file of a scan
+type scanFileResult struct {
+	FilePath string                 `json:"file_path"`
+	Result   *models.AnalysisResult `json:"result,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// scanStatus is the progress of a scan job, as returned by get_scan_status
+type scanStatus struct {
+	JobID          string  `json:"job_id"`
+	Status         string  `json:"status"`
+	Path           string  `json:"path"`
+	FilesTotal     int     `json:"files_total"`
+	FilesDone      int     `json:"files_done"`
+	IssuesFound    int     `json:"issues_found"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	// ETASeconds is the estimated time to completion, known once a file
+	// has finished and only while the job runs
+	ETASeconds *float64 `json:"eta_seconds,omitempty"`
+}
+
+// scanJob is a background analysis of a set of files
+type scanJob struct {
+	ID string
+	// SessionID is the session that started the job; only that session can
+	// see or cancel it
+	SessionID string
+	Path      string
+	Files     []string
+	StartedAt time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	status     string
+	finishedAt time.Time
+	results    []scanFileResult
+	issues     int
+}
+
+// scanTask asks a worker to analyze one file of a job
+type scanTask struct {
+	job  *scanJob
+	path string
+}
+
+// scanManager tracks scan jobs and feeds their files to a pool of workers
+// shared by all jobs
+type scanManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*scanJob
+	order []string
+
+	tasks   chan scanTask
+	workers int
+	start   sync.Once
+}
+
+// newScanManager creates a manager whose pool has workers goroutines
+func newScanManager(workers int) *scanManager {
+	return &scanManager{
+		jobs:    make(map[string]*scanJob),
+		tasks:   make(chan scanTask),
+		workers: workers,
+	}
+}
+
+// get returns the session's job with id, or nil if it is unknown, was
+// forgotten or belongs to another session
+func (sm *scanManager) get(sessionID string, id string) *scanJob {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if job := sm.jobs[id]; job != nil && job.SessionID == sessionID {
+		return job
+	}
+	return nil
+}
+
+// add registers a job, forgetting the oldest finished jobs of its session
+// beyond MAX_SCAN_JOBS. Running jobs are never forgotten.
+func (sm *scanManager) add(job *scanJob) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.jobs[job.ID] = job
+	sm.order = append(sm.order, job.ID)
+
+	count := 0
+	for _, id := range sm.order {
+		if sm.jobs[id].SessionID == job.SessionID {
+			count++
+		}
+	}
+
+	for i := 0; count > MAX_SCAN_JOBS && i < len(sm.order); {
+		id := sm.order[i]
+		if sm.jobs[id].SessionID != job.SessionID || sm.jobs[id].snapshot().Status == SCAN_RUNNING {
+			i++
+			continue
+		}
+		delete(sm.jobs, id)
+		sm.order = append(sm.order[:i], sm.order[i+1:]...)
+		count--
+	}
+}
+
+// forget cancels and drops every job of a session that has ended
+func (sm *scanManager) forget(sessionID string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	order := []string{}
+	for _, id := range sm.order {
+		if job := sm.jobs[id]; job.SessionID == sessionID {
+			job.stop()
+			delete(sm.jobs, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	sm.order = order
+}
+
+// startScan creates a job of a session analyzing files and queues them for
+// the worker pool. It returns immediately.
+func (s *MCPServer) startScan(sessionID string, path string, files []string) *scanJob {
+	idBytes := make([]byte, 8)
+	rand.Read(idBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &scanJob{
+		ID:        hex.EncodeToString(idBytes),
+		SessionID: sessionID,
+		Path:      path,
+		Files:     files,
+		StartedAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+		status:    SCAN_RUNNING,
+	}
+	s.scans.add(job)
+
+	s.scans.start.Do(func() {
+		for i := 0; i < s.scans.workers; i++ {
+			go s.scanWorker()
+		}
+	})
+
+	go func() {
+		for _, file := range files {
+			select {
+			case s.scans.tasks <- scanTask{job: job, path: file}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return job
+}
+
+// scanWorker analyzes queued files until the process exits
+func (s *MCPServer) scanWorker() {
+	for task := range s.scans.tasks {
+		if task.job.ctx.Err() != nil {
+			continue
+		}
+		result := s.scanFile(task.job.ctx, task.path)
+		if task.job.ctx.Err() != nil {
+			continue
+		}
+		if result.Result != nil {
+			s.storeResult(task.job.SessionID, task.path, task.path, result.Result)
+		}
+		task.job.record(result)
+	}
+}
+
+// scanFile reads and analyzes one file with the analyzer for its language
+func (s *MCPServer) scanFile(ctx context.Context, path string) scanFileResult {
+	code, err := readSourceFile(path)
+	if err != nil {
+		return scanFileResult{FilePath: path, Error: err.Error()}
+	}
+
+	_, analyzer, mcpErr := s.resolveAnalyzer(ctx, "", code, path)
+	if mcpErr != nil {
+		return scanFileResult{FilePath: path, Error: mcpErr.Message}
+	}
+
+	result, err := analyzer.Analyze(ctx, code, path)
+	if err != nil {
+		return scanFileResult{FilePath: path, Error: err.Error()}
+	}
+	return scanFileResult{FilePath: path, Result: result}
+}
+
+// record stores the outcome of one file, completing the job after the last
+func (job *scanJob) record(result scanFileResult) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.status != SCAN_RUNNING {
+		return
+	}
+
+	job.results = append(job.results, result)
+	if result.Result != nil {
+		job.issues += len(result.Result.Issues)
+	}
+	if len(job.results) == len(job.Files) {
+		job.status = SCAN_COMPLETED
+		job.finishedAt = time.Now()
+		job.cancel()
+	}
+}
+
+// stop cancels a running job, keeping the results recorded so far. It
+// returns false if the job had already finished.
+func (job *scanJob) stop() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.status != SCAN_RUNNING {
+		return false
+	}
+	job.status = SCAN_CANCELLED
+	job.finishedAt = time.Now()
+	job.cancel()
+	return true
+}
+
+// snapshot returns the current progress of the job
+func (job *scanJob) snapshot() scanStatus {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	end := time.Now()
+	if job.status != SCAN_RUNNING {
+		end = job.finishedAt
+	}
+	elapsed := end.Sub(job.StartedAt).Seconds()
+
+	status := scanStatus{
+		JobID:          job.ID,
+		Status:         job.status,
+		Path:           job.Path,
+		FilesTotal:     len(job.Files),
+		FilesDone:      len(job.results),
+		IssuesFound:    job.issues,
+		ElapsedSeconds: elapsed,
+	}
+	if job.status == SCAN_RUNNING && status.FilesDone > 0 {
+		eta := elapsed / float64(status.FilesDone) * float64(status.FilesTotal-status.FilesDone)
+		status.ETASeconds = &eta
+	}
+	return status
+}
+
+// fileResults returns a copy of the results recorded so far
+func (job *scanJob) fileResults() []scanFileResult {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return append([]scanFileResult{}, job.results...)
+}
+
+// handleStartScan handles the start_scan tool
+func (s *MCPServer) handleStartScan(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	path, _ := args["path"].(string)
+	resolved, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), path)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Cannot scan %s: %v", path, err)}
+	}
+
+	files := []string{}
+	if info.IsDir() {
+		err = s.walkSourceFiles(ctx, resolved, func(file string) error {
+			files = append(files, file)
+			return nil
+		})
+		if err != nil {
+			return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Could not list files under %s: %v", path, err)}
+		}
+	} else {
+		files = append(files, resolved)
+	}
+	if len(files) == 0 {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("No supported source files found under %s", path)}
+	}
+
+	job := s.startScan(sessionFrom(ctx).id, resolved, files)
+	return scanStatusResponse(job.snapshot(), fmt.Sprintf("Started scan %s of %d file(s) under %s. Poll get_scan_status with this job_id.", job.ID, len(files), resolved)), nil
+}
+
+// handleGetScanStatus handles the get_scan_status tool
+func (s *MCPServer) handleGetScanStatus(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	job, mcpErr := s.lookupScan(ctx, args)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	status := job.snapshot()
+	summary := fmt.Sprintf("Scan %s is %s: %d of %d file(s) analyzed, %d issue(s) found so far.",
+		status.JobID, status.Status, status.FilesDone, status.FilesTotal, status.IssuesFound)
+	if status.ETASeconds != nil {
+		summary += fmt.Sprintf(" Estimated time remaining: %s.", (time.Duration(*status.ETASeconds) * time.Second).String())
+	}
+	return scanStatusResponse(status, summary), nil
+}
+
+// handleGetScanResult handles the get_scan_result tool. Cancelled jobs
+// return the files analyzed before they were stopped.
+func (s *MCPServer) handleGetScanResult(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	job, mcpErr := s.lookupScan(ctx, args)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	status := job.snapshot()
+	if status.Status == SCAN_RUNNING {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Scan %s is still running (%d of %d files); poll get_scan_status until it completes", job.ID, status.FilesDone, status.FilesTotal)}
+	}
+
+	results := job.fileResults()
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatScanReport(status, results),
+			},
+		},
+		"structuredContent": map[string]interface{}{
+			"status": status,
+			"files":  results,
+		},
+	}
+	return response, nil
+}
+
+// handleCancelScan handles the cancel_scan tool
+func (s *MCPServer) handleCancelScan(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	job, mcpErr := s.lookupScan(ctx, args)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+
+	summary := fmt.Sprintf("Scan %s cancelled; results for the files already analyzed are kept.", job.ID)
+	if !job.stop() {
+		summary = fmt.Sprintf("Scan %s had already finished.", job.ID)
+	}
+	return scanStatusResponse(job.snapshot(), summary), nil
+}
+
+// lookupScan returns the job of the request's session named by the job_id
+// argument
+func (s *MCPServer) lookupScan(ctx context.Context, args map[string]interface{}) (*scanJob, *MCPError) {
+	id, _ := args["job_id"].(string)
+	job := s.scans.get(sessionFrom(ctx).id, id)
+	if job == nil {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Unknown scan job: %s", id)}
+	}
+	return job, nil
+}
+
+// scanStatusResponse builds a tool result carrying a job status
+func scanStatusResponse(status scanStatus, summary string) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": summary,
+			},
+		},
+		"structuredContent": status,
+	}
+}
+
+// formatScanReport formats the results of a finished scan as markdown
+func formatScanReport(status scanStatus, results []scanFileResult) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Security Scan Report\n\n")
+	sb.WriteString(fmt.Sprintf("**Scan**: %s\n", status.JobID))
+	sb.WriteString(fmt.Sprintf("**Status**: %s (%d of %d files)\n", status.Status, status.FilesDone, status.FilesTotal))
+	sb.WriteString(formatProjectReport(buildProjectReport(status.Path, results)))
+
+	return sb.String()
+}
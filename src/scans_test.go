@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newScanWorkspace creates Java files in a temporary workspace and makes it
+// the working directory, which is the only root of test sessions
+func newScanWorkspace(t *testing.T, files ...string) string {
+	t.Helper()
+
+	workspace := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(workspace, file)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte("public class A {}"), 0o644)
+	}
+	t.Chdir(workspace)
+	return workspace
+}
+
+// callScanTool calls a scan tool and decodes its structuredContent into out
+func callScanTool(t *testing.T, server *MCPServer, name string, arguments map[string]interface{}, out interface{}) *MCPError {
+	t.Helper()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{"name": name, "arguments": arguments})
+	if response.Error != nil {
+		return response.Error
+	}
+	jsonData, _ := json.Marshal(response.Result.(map[string]interface{})["structuredContent"])
+	if err := json.Unmarshal(jsonData, out); err != nil {
+		t.Fatal(err)
+	}
+	return nil
+}
+
+func TestScanJob(t *testing.T) {
+	newFakeLLM(t, `[{"title":"SQL Injection","severity":"HIGH","line_number":1}]`)
+	newScanWorkspace(t, "src/A.java", "src/B.java", "src/c/C.java", "node_modules/D.java")
+	server := NewMCPServer()
+
+	var status scanStatus
+	if err := callScanTool(t, server, "start_scan", map[string]interface{}{"path": "src"}, &status); err != nil {
+		t.Fatalf("start_scan failed: %s", err.Message)
+	}
+	if status.FilesTotal != 3 {
+		t.Fatalf("expected 3 files to scan, got %d", status.FilesTotal)
+	}
+
+	jobID := map[string]interface{}{"job_id": status.JobID}
+	deadline := time.Now().Add(5 * time.Second)
+	for status.Status == SCAN_RUNNING {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan did not complete: %+v", status)
+		}
+		time.Sleep(20 * time.Millisecond)
+		callScanTool(t, server, "get_scan_status", jobID, &status)
+	}
+
+	var result struct {
+		Status scanStatus       `json:"status"`
+		Files  []scanFileResult `json:"files"`
+	}
+	if err := callScanTool(t, server, "get_scan_result", jobID, &result); err != nil {
+		t.Fatalf("get_scan_result failed: %s", err.Message)
+	}
+	if result.Status.Status != SCAN_COMPLETED || result.Status.IssuesFound != 3 || len(result.Files) != 3 {
+		t.Errorf("unexpected scan result: %+v", result)
+	}
+	for _, file := range result.Files {
+		if file.Result == nil || len(file.Result.Issues) != 1 {
+			t.Errorf("unexpected result for %s: %+v", file.FilePath, file)
+			continue
+		}
+		if _, issue := server.reports.findIssue("test", file.Result.Issues[0].Fingerprint); issue == nil {
+			t.Errorf("expected the finding in %s to be stored for lookup by fingerprint", file.FilePath)
+		}
+	}
+
+	if err := callScanTool(t, server, "get_scan_status", map[string]interface{}{"job_id": "missing"}, &status); err == nil {
+		t.Error("expected an unknown job to be rejected")
+	}
+
+	other := newSession("other", nil)
+	other.initialize(SUPPORTED_PROTOCOL_VERSIONS[0], nil)
+	request := &MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/call", Params: map[string]interface{}{"name": "get_scan_result", "arguments": jobID}}
+	if response := server.handleRequest(context.Background(), other, request); response.Error == nil {
+		t.Error("expected the job to be hidden from another session")
+	}
+}
+
+func TestCancelScan(t *testing.T) {
+	started := make(chan struct{}, 10)
+	aborted := make(chan struct{}, 10)
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		started <- struct{}{}
+		<-r.Context().Done()
+		aborted <- struct{}{}
+	}))
+	defer llm.Close()
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+
+	newScanWorkspace(t, "A.java", "B.java", "C.java")
+	server := NewMCPServer()
+
+	var status scanStatus
+	if err := callScanTool(t, server, "start_scan", map[string]interface{}{"path": "."}, &status); err != nil {
+		t.Fatalf("start_scan failed: %s", err.Message)
+	}
+	<-started
+
+	jobID := map[string]interface{}{"job_id": status.JobID}
+	if err := callScanTool(t, server, "get_scan_result", jobID, &status); err == nil {
+		t.Error("expected get_scan_result to fail while the scan runs")
+	}
+
+	callScanTool(t, server, "cancel_scan", jobID, &status)
+	if status.Status != SCAN_CANCELLED {
+		t.Errorf("expected the scan to be cancelled, got %+v", status)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("LLM request of the cancelled scan was not aborted")
+	}
+
+	if err := callScanTool(t, server, "get_scan_result", jobID, &struct{}{}); err != nil {
+		t.Errorf("expected partial results of a cancelled scan, got %s", err.Message)
+	}
+}
+
+func TestStartScanOutsideRoots(t *testing.T) {
+	newScanWorkspace(t, "A.java")
+	server := NewMCPServer()
+
+	if err := callScanTool(t, server, "start_scan", map[string]interface{}{"path": "../"}, &struct{}{}); err == nil {
+		t.Error("expected a path outside the workspace to be rejected")
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// severityEnum lists the values of models.SeverityLevel
+var severityEnum = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+// securityIssueSchema mirrors models.SecurityIssue
+func securityIssueSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":            map[string]interface{}{"type": "string", "description": "Issue identifier, unique within the report"},
+			"title":         map[string]interface{}{"type": "string"},
+			"description":   map[string]interface{}{"type": "string"},
+			"severity":      map[string]interface{}{"type": "string", "enum": severityEnum},
+			"line_number":   map[string]interface{}{"type": "integer", "description": "1-based line, 0 when unknown"},
+			"column_number": map[string]interface{}{"type": "integer", "description": "1-based column, 0 when unknown"},
+			"file_path":     map[string]interface{}{"type": "string"},
+			"code_snippet":  map[string]interface{}{"type": "string"},
+			"remediation":   map[string]interface{}{"type": "string"},
+			"references": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"commit":      map[string]interface{}{"type": "string", "description": "Commit that introduced the line, for scans of a commit range"},
+			"fingerprint": map[string]interface{}{"type": "string", "description": "Stable identifier of the issue across analyses"},
+			"rule_id":     map[string]interface{}{"type": "string", "description": "ID of the rule the issue violates, see get_rule"},
+		},
+		"required": []string{"id", "title", "severity", "line_number"},
+	}
+}
+
+// securityRuleSchema mirrors ruleEntry, the structuredContent returned by
+// get_rule
+func securityRuleSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id":       map[string]interface{}{"type": "string"},
+			"title":    map[string]interface{}{"type": "string"},
+			"category": map[string]interface{}{"type": "string"},
+			"cwe": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+			"owasp":            map[string]interface{}{"type": "string", "description": "OWASP Top 10 (2021) category"},
+			"default_severity": map[string]interface{}{"type": "string", "enum": severityEnum},
+			"prompt":           map[string]interface{}{"type": "string", "description": "What the analyzer is told to look for"},
+			"languages": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "string"},
+			},
+		},
+		"required": []string{"id", "title", "category", "cwe", "default_severity", "prompt", "languages"},
+	}
+}
+
+// analysisResultSchema mirrors models.AnalysisResult, the structuredContent
+// returned by analyze_security
+func analysisResultSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"language": map[string]interface{}{"type": "string"},
+			"issues": map[string]interface{}{
+				"type":  "array",
+				"items": securityIssueSchema(),
+			},
+			"summary": map[string]interface{}{"type": "string"},
+			"analysis_metadata": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"analysis_time":     map[string]interface{}{"type": "string"},
+					"issues_found":      map[string]interface{}{"type": "integer"},
+					"critical_count":    map[string]interface{}{"type": "integer"},
+					"high_count":        map[string]interface{}{"type": "integer"},
+					"medium_count":      map[string]interface{}{"type": "integer"},
+					"low_count":         map[string]interface{}{"type": "integer"},
+					"detected_language": map[string]interface{}{"type": "string"},
+					"provider":          map[string]interface{}{"type": "string", "description": "LLM provider that produced the findings (lmstudio or mcp_sampling)"},
+					"errors": map[string]interface{}{
+						"type":  "array",
+						"items": map[string]interface{}{"type": "string"},
+					},
+				},
+				"required": []string{"analysis_time", "issues_found", "detected_language"},
+			},
+		},
+		"required": []string{"language", "issues", "summary", "analysis_metadata"},
+	}
+}
+
+// fileResultSchema mirrors scanFileResult, the outcome of analyzing one file
+// read from disk
+func fileResultSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"file_path": map[string]interface{}{"type": "string"},
+			"result":    analysisResultSchema(),
+			"error":     map[string]interface{}{"type": "string", "description": "Why the file could not be analyzed; result is absent"},
+		},
+		"required": []string{"file_path"},
+	}
+}
+
+// fileResultsSchema describes the per-file results returned by analyze_file
+func fileResultsSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"files": map[string]interface{}{
+				"type":  "array",
+				"items": fileResultSchema(),
+			},
+		},
+		"required": []string{"files"},
+	}
+}
+
+// projectReportSchema mirrors projectReport, the structuredContent returned
+// by analyze_project
+func projectReportSchema() map[string]interface{} {
+	count := map[string]interface{}{"type": "integer"}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path":           map[string]interface{}{"type": "string"},
+			"files_analyzed": count,
+			"files_failed":   count,
+			"issues_found":   count,
+			"critical_count": count,
+			"high_count":     count,
+			"medium_count":   count,
+			"low_count":      count,
+			"top_risk_files": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"file_path":      map[string]interface{}{"type": "string"},
+						"risk_score":     map[string]interface{}{"type": "integer", "description": "Findings weighted by severity: critical 10, high 5, medium 2, low 1"},
+						"critical_count": count,
+						"high_count":     count,
+						"medium_count":   count,
+						"low_count":      count,
+					},
+				},
+			},
+			"files": map[string]interface{}{
+				"type":  "array",
+				"items": fileResultSchema(),
+			},
+		},
+		"required": []string{"path", "files_analyzed", "issues_found", "top_risk_files", "files"},
+	}
+}
+
+// validateArguments checks tool arguments against an input schema. It
+// supports the subset of JSON Schema our tools use: type, properties,
+// required, enum and items.
+func validateArguments(schema map[string]interface{}, args map[string]interface{}) error {
+	return validateValue(schema, args, "arguments")
+}
+
+// validateValue checks value against schema, naming it path in errors
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("missing required argument '%s'", name)
+			}
+		}
+
+		// Check properties in a stable order so the first error is deterministic
+		properties, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := validateValue(propertySchema, object[name], name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("'%s' must be an array", path)
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				if err := validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("'%s' must be a string", path)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("'%s' must be an integer", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("'%s' must be a number", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("'%s' must be a boolean", path)
+		}
+	}
+
+	if enum, ok := schema["enum"].([]string); ok {
+		for _, allowed := range enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("'%s' must be one of %v, got %v", path, enum, value)
+	}
+	return nil
+}
+
+// explanationSchema describes the structuredContent returned by explain_issue
+func explanationSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"issue":           securityIssueSchema(),
+			"provider":        map[string]interface{}{"type": "string"},
+			"attack_scenario": map[string]interface{}{"type": "string", "description": "How an attacker exploits the issue, with example malicious input"},
+			"exploitability":  map[string]interface{}{"type": "string", "description": "Why the current code is exploitable"},
+			"cwe_background":  map[string]interface{}{"type": "string", "description": "The CWE entry describing the weakness"},
+			"remediation":     map[string]interface{}{"type": "string", "description": "How the remediation addresses the issue"},
+		},
+		"required": []string{"issue", "attack_scenario", "exploitability", "cwe_background", "remediation"},
+	}
+}
+
+// fixSchema describes the structuredContent returned by suggest_fix
+func fixSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"file_path":  map[string]interface{}{"type": "string"},
+			"patch":      map[string]interface{}{"type": "string", "description": "Unified diff against the submitted code, checked to apply cleanly"},
+			"fixed_code": map[string]interface{}{"type": "string"},
+			"issues": map[string]interface{}{
+				"type":  "array",
+				"items": securityIssueSchema(),
+			},
+			"provider": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"patch", "fixed_code", "issues"},
+	}
+}
+
+// fixVerificationSchema mirrors fixVerification, the structuredContent
+// returned by verify_fix
+func fixVerificationSchema() map[string]interface{} {
+	issues := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "array",
+			"description": description,
+			"items":       securityIssueSchema(),
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"resolved":   issues("Original findings absent from the patched code"),
+			"persisting": issues("Findings of the patched code matching an original finding"),
+			"new":        issues("Findings of the patched code absent from the original code"),
+			"verified":   map[string]interface{}{"type": "boolean", "description": "True when no finding persists and none is new"},
+		},
+		"required": []string{"resolved", "persisting", "new", "verified"},
+	}
+}
+
+// reportComparisonSchema mirrors reportComparison, the structuredContent
+// returned by compare_reports
+func reportComparisonSchema() map[string]interface{} {
+	issues := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"type":        "array",
+			"description": description,
+			"items":       securityIssueSchema(),
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"new":       issues("Findings of the later result missing from the earlier one"),
+			"fixed":     issues("Findings of the earlier result missing from the later one"),
+			"unchanged": issues("Findings of the later result also in the earlier one"),
+		},
+		"required": []string{"new", "fixed", "unchanged"},
+	}
+}
+
+// threatModelSchema mirrors threatModel, the structuredContent returned by
+// generate_threat_model
+func threatModelSchema() map[string]interface{} {
+	list := func(properties map[string]interface{}, required ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":       "object",
+				"properties": properties,
+				"required":   required,
+			},
+		}
+	}
+	text := map[string]interface{}{"type": "string"}
+	texts := map[string]interface{}{"type": "array", "items": text}
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"files": texts,
+			"components": list(map[string]interface{}{
+				"id":          text,
+				"name":        text,
+				"type":        map[string]interface{}{"type": "string", "description": "process or external_entity"},
+				"file":        text,
+				"description": text,
+			}, "id", "name", "type"),
+			"data_stores": list(map[string]interface{}{
+				"id":   text,
+				"name": text,
+				"data": text,
+				"file": text,
+			}, "id", "name"),
+			"trust_boundaries": list(map[string]interface{}{
+				"id":      text,
+				"name":    text,
+				"members": texts,
+			}, "id", "name", "members"),
+			"entry_points": list(map[string]interface{}{
+				"name":        text,
+				"component":   text,
+				"file":        text,
+				"line_number": map[string]interface{}{"type": "integer"},
+				"description": text,
+			}, "name", "component"),
+			"data_flows": list(map[string]interface{}{
+				"from": text,
+				"to":   text,
+				"data": text,
+			}, "from", "to"),
+			"threats": list(map[string]interface{}{
+				"category":   map[string]interface{}{"type": "string", "description": "STRIDE category"},
+				"target":     text,
+				"threat":     text,
+				"mitigation": text,
+				"status":     map[string]interface{}{"type": "string", "enum": []string{"present", "partial", "missing"}},
+				"evidence":   text,
+			}, "category", "target", "threat", "mitigation", "status"),
+			"diagram":  map[string]interface{}{"type": "string", "description": "Mermaid flowchart of the data flows"},
+			"provider": text,
+		},
+		"required": []string{"files", "components", "data_stores", "trust_boundaries", "entry_points", "data_flows", "threats", "diagram"},
+	}
+}
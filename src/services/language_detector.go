@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -91,6 +92,13 @@ func (ld *LanguageDetector) DetectFromExtension(filePath string) models.Language
 
 // DetectFromContent detects language based on code content using pattern matching
 func (ld *LanguageDetector) DetectFromContent(code string) models.LanguageType {
+	detectedLang, _ := ld.detectFromContent(code)
+	return detectedLang
+}
+
+// detectFromContent returns the best-scoring language along with the number
+// of patterns each language matched
+func (ld *LanguageDetector) detectFromContent(code string) (models.LanguageType, map[models.LanguageType]int) {
 	scores := make(map[models.LanguageType]int)
 
 	// Check each language's patterns
@@ -113,11 +121,16 @@ func (ld *LanguageDetector) DetectFromContent(code string) models.LanguageType {
 		}
 	}
 
-	return detectedLang
+	return detectedLang, scores
 }
 
 // Detect detects language using extension first, then falls back to content analysis
 func (ld *LanguageDetector) Detect(code string, filePath string) models.LanguageType {
+	return ld.DetectContext(context.Background(), code, filePath)
+}
+
+// DetectContext is Detect with its decisions logged through ctx
+func (ld *LanguageDetector) DetectContext(ctx context.Context, code string, filePath string) models.LanguageType {
 	// Try extension-based detection first
 	if filePath != "" {
 		lang := ld.DetectFromExtension(filePath)
@@ -125,18 +138,25 @@ func (ld *LanguageDetector) Detect(code string, filePath string) models.Language
 			// For .ts and .js files, verify it's actually React code
 			if lang == models.REACT_TYPESCRIPT || lang == models.REACT_JAVASCRIPT {
 				// Check if code contains React patterns
-				contentLang := ld.DetectFromContent(code)
+				contentLang, scores := ld.detectFromContent(code)
 				if contentLang == lang {
+					Log(ctx, LOG_INFO, "detector", "Detected %s from extension of %s, confirmed by content (scores %v)", lang, filePath, scores)
 					return lang
 				}
+				Log(ctx, LOG_DEBUG, "detector", "Extension of %s suggests %s but content scored %s (scores %v)", filePath, lang, contentLang, scores)
 			} else {
+				Log(ctx, LOG_INFO, "detector", "Detected %s from extension of %s", lang, filePath)
 				return lang
 			}
+		} else {
+			Log(ctx, LOG_DEBUG, "detector", "No language registered for extension of %s", filePath)
 		}
 	}
 
 	// Fall back to content-based detection
-	return ld.DetectFromContent(code)
+	lang, scores := ld.detectFromContent(code)
+	Log(ctx, LOG_INFO, "detector", "Detected %s from content (scores %v)", lang, scores)
+	return lang
 }
 
 // GetSupportedLanguages returns a list of all supported languages with metadata
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -56,6 +57,24 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// LLM providers recorded in analysis metadata
+const (
+	PROVIDER_LMSTUDIO = "lmstudio"
+	PROVIDER_SAMPLING = "mcp_sampling"
+)
+
+// Sampler completes chat messages with a model other than LMStudio, such as
+// the MCP client's model through sampling/createMessage
+type Sampler func(ctx context.Context, messages []Message) (string, error)
+
+type samplerKey struct{}
+
+// WithSampler returns a context whose LLM calls fall back to sampler when
+// LMStudio is unavailable
+func WithSampler(ctx context.Context, sampler Sampler) context.Context {
+	return context.WithValue(ctx, samplerKey{}, sampler)
+}
+
 // NewLLMService creates a new LLM service with configuration
 func NewLLMService() *LLMService {
 	baseURL := os.Getenv("LMSTUDIO_BASE_URL")
@@ -80,8 +99,10 @@ func NewLLMService() *LLMService {
 	}
 }
 
-// Analyze sends code to LLM for security analysis
-func (llm *LLMService) Analyze(code string, prompt string) (string, error) {
+// Analyze sends code to LLM for security analysis and returns the response
+// along with the provider that produced it. Cancelling ctx aborts the HTTP
+// request to LMStudio.
+func (llm *LLMService) Analyze(ctx context.Context, code string, prompt string) (string, string, error) {
 	messages := []Message{
 		{
 			Role:    "system",
@@ -93,6 +114,40 @@ func (llm *LLMService) Analyze(code string, prompt string) (string, error) {
 		},
 	}
 
+	return llm.Complete(ctx, messages)
+}
+
+// Complete sends messages to LMStudio and returns the reply along with the
+// provider that produced it. If LMStudio fails and ctx carries a Sampler,
+// the same messages are sent through the sampler instead.
+func (llm *LLMService) Complete(ctx context.Context, messages []Message) (string, string, error) {
+	content, err := llm.send(ctx, messages)
+	if err == nil {
+		return content, PROVIDER_LMSTUDIO, nil
+	}
+
+	sampler, ok := ctx.Value(samplerKey{}).(Sampler)
+	if !ok || ctx.Err() != nil {
+		return "", "", err
+	}
+
+	Log(ctx, LOG_WARNING, "llm", "LMStudio unavailable, falling back to MCP sampling")
+	content, samplingErr := sampler(ctx, messages)
+	if samplingErr != nil {
+		return "", "", fmt.Errorf("%w; sampling fallback failed: %v", err, samplingErr)
+	}
+	return content, PROVIDER_SAMPLING, nil
+}
+
+// send performs a single chat completion request against the LMStudio
+// endpoint, logging its failure
+func (llm *LLMService) send(ctx context.Context, messages []Message) (content string, err error) {
+	defer func() {
+		if err != nil {
+			Log(ctx, LOG_ERROR, "llm", "LMStudio request failed: %v", err)
+		}
+	}()
+
 	request := LLMRequest{
 		Model:       llm.model,
 		Messages:    messages,
@@ -105,7 +160,7 @@ func (llm *LLMService) Analyze(code string, prompt string) (string, error) {
 	}
 
 	url := fmt.Sprintf("%s/v1/chat/completions", llm.baseURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -115,6 +170,9 @@ func (llm *LLMService) Analyze(code string, prompt string) (string, error) {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", llm.apiKey))
 	}
 
+	Log(ctx, LOG_INFO, "llm", "Sending %d message(s) to %s at %s (%d bytes)", len(messages), llm.model, url, len(jsonData))
+	startTime := time.Now()
+
 	resp, err := llm.client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to send request: %w", err)
@@ -140,9 +198,17 @@ func (llm *LLMService) Analyze(code string, prompt string) (string, error) {
 		return "", fmt.Errorf("no choices in LLM response")
 	}
 
+	Log(ctx, LOG_DEBUG, "llm", "LMStudio replied in %s (%d prompt tokens, %d completion tokens)",
+		time.Since(startTime).Round(time.Millisecond), llmResp.Usage.PromptTokens, llmResp.Usage.CompletionTokens)
+
 	return llmResp.Choices[0].Message.Content, nil
 }
 
+// Model returns the name of the LMStudio model used for analysis
+func (llm *LLMService) Model() string {
+	return llm.model
+}
+
 // HealthCheck verifies LLM service availability
 func (llm *LLMService) HealthCheck() (bool, error) {
 	url := fmt.Sprintf("%s/v1/models", llm.baseURL)
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+)
+
+// LogLevel is a log severity, ordered as the syslog levels used by MCP logging
+type LogLevel int
+
+const (
+	LOG_DEBUG LogLevel = iota
+	LOG_INFO
+	LOG_NOTICE
+	LOG_WARNING
+	LOG_ERROR
+	LOG_CRITICAL
+	LOG_ALERT
+	LOG_EMERGENCY
+)
+
+var logLevelNames = []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"}
+
+// String returns the MCP name of the level
+func (level LogLevel) String() string {
+	if level < LOG_DEBUG || level > LOG_EMERGENCY {
+		return fmt.Sprintf("level(%d)", int(level))
+	}
+	return logLevelNames[level]
+}
+
+// ParseLogLevel converts an MCP level name into a LogLevel
+func ParseLogLevel(name string) (LogLevel, bool) {
+	for i, levelName := range logLevelNames {
+		if levelName == name {
+			return LogLevel(i), true
+		}
+	}
+	return LOG_DEBUG, false
+}
+
+// Logger receives log entries. logger names the component that produced
+// the entry, such as "llm" or "detector".
+type Logger func(level LogLevel, logger string, message string)
+
+type loggerKey struct{}
+
+// WithLogger returns a context whose log entries are delivered to logger
+func WithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Log formats and records an entry through the logger carried by ctx. It is
+// a no-op when ctx has no logger.
+func Log(ctx context.Context, level LogLevel, logger string, format string, args ...interface{}) {
+	if log, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		log(level, logger, fmt.Sprintf(format, args...))
+	}
+}
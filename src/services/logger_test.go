@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for level := LOG_DEBUG; level <= LOG_EMERGENCY; level++ {
+		parsed, ok := ParseLogLevel(level.String())
+		if !ok || parsed != level {
+			t.Errorf("ParseLogLevel(%q) = %v, %v", level.String(), parsed, ok)
+		}
+	}
+
+	if _, ok := ParseLogLevel("verbose"); ok {
+		t.Error("expected unknown level to be rejected")
+	}
+}
+
+func TestLog(t *testing.T) {
+	// Without a logger entries are dropped
+	Log(context.Background(), LOG_ERROR, "test", "dropped")
+
+	var got string
+	ctx := WithLogger(context.Background(), func(level LogLevel, logger string, message string) {
+		got = level.String() + " " + logger + ": " + message
+	})
+	Log(ctx, LOG_NOTICE, "test", "%d files", 3)
+
+	if got != "notice test: 3 files" {
+		t.Errorf("unexpected log entry %q", got)
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+// session holds the state of one connected client. The stdio transport
+// serves a single session; the HTTP transport creates one per Mcp-Session-Id.
+type session struct {
+	id string
+
+	// ctx lives as long as the session; end cancels it when the session is
+	// closed, stopping the background work it started
+	ctx context.Context
+	end context.CancelFunc
+
+	// send delivers server-initiated messages that are not tied to a
+	// request. It is nil while the client has no stream open; generation
+	// identifies the stream currently attached.
+	sendMu     sync.Mutex
+	send       func(message interface{})
+	generation int
+
+	// inflight maps the key of each running request to its cancel function
+	inflightMu sync.Mutex
+	inflight   map[string]context.CancelFunc
+
+	// pending maps the key of each server-initiated request to the channel
+	// awaiting the client's response
+	pendingMu sync.Mutex
+	pending   map[string]chan *MCPRequest
+	nextID    atomic.Int64
+
+	// Lifecycle state set by initialize
+	stateMu            sync.RWMutex
+	initialized        bool
+	protocolVersion    string
+	clientCapabilities map[string]interface{}
+
+	// logLevel is the minimum level sent as notifications/message, set by
+	// logging/setLevel
+	logLevel services.LogLevel
+
+	// roots are the resolved workspace directories reported by roots/list.
+	// rootsGeneration is bumped on notifications/roots/list_changed so a
+	// fetch that raced with the change is discarded.
+	rootsMu         sync.Mutex
+	roots           []string
+	rootsValid      bool
+	rootsGeneration int
+	rootsFetchMu    sync.Mutex
+
+	// subscriptions maps each subscribed resource URI to the function that
+	// stops its watcher
+	subscriptionsMu sync.Mutex
+	subscriptions   map[string]context.CancelFunc
+
+	// lastActive is when the client last used the session, in Unix
+	// nanoseconds, so the HTTP transport can expire abandoned sessions
+	lastActive atomic.Int64
+
+	// remote marks sessions of network clients. Their roots name
+	// directories on the client's machine, so file access is confined to
+	// serverRoot instead, and disabled when it is empty.
+	remote     bool
+	serverRoot string
+}
+
+// newSession creates a session whose server-initiated messages go to send
+func newSession(id string, send func(message interface{})) *session {
+	ctx, end := context.WithCancel(context.Background())
+	sess := &session{
+		id:            id,
+		ctx:           ctx,
+		end:           end,
+		send:          send,
+		inflight:      make(map[string]context.CancelFunc),
+		pending:       make(map[string]chan *MCPRequest),
+		logLevel:      DEFAULT_LOG_LEVEL,
+		subscriptions: make(map[string]context.CancelFunc),
+	}
+	sess.touch()
+	return sess
+}
+
+// touch records that the client used the session
+func (sess *session) touch() {
+	sess.lastActive.Store(time.Now().UnixNano())
+}
+
+// idle reports whether the session has gone unused for longer than timeout
+// as of now. Sessions with a request running or a stream open are in use.
+func (sess *session) idle(now time.Time, timeout time.Duration) bool {
+	if sess.streaming() {
+		return false
+	}
+	sess.inflightMu.Lock()
+	running := len(sess.inflight)
+	sess.inflightMu.Unlock()
+
+	return running == 0 && now.Sub(time.Unix(0, sess.lastActive.Load())) > timeout
+}
+
+// initialize records the negotiated protocol version and the capabilities
+// declared by the client. It returns false if the session was already
+// initialized.
+func (sess *session) initialize(protocolVersion string, capabilities map[string]interface{}) bool {
+	sess.stateMu.Lock()
+	defer sess.stateMu.Unlock()
+
+	if sess.initialized {
+		return false
+	}
+	sess.initialized = true
+	sess.protocolVersion = protocolVersion
+	sess.clientCapabilities = capabilities
+	return true
+}
+
+// isInitialized reports whether initialize has been handled
+func (sess *session) isInitialized() bool {
+	sess.stateMu.RLock()
+	defer sess.stateMu.RUnlock()
+	return sess.initialized
+}
+
+// clientSupports reports whether the client declared capability
+func (sess *session) clientSupports(capability string) bool {
+	sess.stateMu.RLock()
+	defer sess.stateMu.RUnlock()
+	_, ok := sess.clientCapabilities[capability]
+	return ok
+}
+
+// setLogLevel changes the minimum level of log notifications
+func (sess *session) setLogLevel(level services.LogLevel) {
+	sess.stateMu.Lock()
+	defer sess.stateMu.Unlock()
+	sess.logLevel = level
+}
+
+// logs reports whether entries at level are sent to the client
+func (sess *session) logs(level services.LogLevel) bool {
+	sess.stateMu.RLock()
+	defer sess.stateMu.RUnlock()
+	return level >= sess.logLevel
+}
+
+// setSender replaces the stream used for server-initiated messages and
+// returns a generation to pass to clearSender when that stream closes
+func (sess *session) setSender(send func(message interface{})) int {
+	sess.sendMu.Lock()
+	defer sess.sendMu.Unlock()
+	sess.send = send
+	sess.generation++
+	return sess.generation
+}
+
+// clearSender detaches the stream of generation unless it has already been
+// replaced by a newer one
+func (sess *session) clearSender(generation int) {
+	sess.sendMu.Lock()
+	defer sess.sendMu.Unlock()
+	if sess.generation == generation {
+		sess.send = nil
+	}
+}
+
+// streaming reports whether a stream for server-initiated messages is open
+func (sess *session) streaming() bool {
+	sess.sendMu.Lock()
+	defer sess.sendMu.Unlock()
+	return sess.send != nil
+}
+
+// notify sends a server-initiated message, dropping it if no stream is open.
+// The write happens outside sendMu so a client that stops reading its stream
+// only stalls the messages sent to it.
+func (sess *session) notify(message interface{}) {
+	sess.sendMu.Lock()
+	send := sess.send
+	sess.sendMu.Unlock()
+
+	if send != nil {
+		send(message)
+	}
+}
+
+// requestKey returns a map key for a JSON-RPC id, keeping 1 and "1" distinct
+func requestKey(id interface{}) string {
+	key, _ := json.Marshal(id)
+	return string(key)
+}
+
+// trackRequest registers an in-flight request and returns its cancellable
+// context along with a function that must be called once it completes. It
+// returns false, registering nothing, if a request with the same ID is
+// still in flight.
+func (sess *session) trackRequest(id interface{}) (context.Context, func(), bool) {
+	key := requestKey(id)
+
+	sess.inflightMu.Lock()
+	if _, ok := sess.inflight[key]; ok {
+		sess.inflightMu.Unlock()
+		return nil, nil, false
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	sess.inflight[key] = cancel
+	sess.inflightMu.Unlock()
+
+	return ctx, func() {
+		sess.inflightMu.Lock()
+		delete(sess.inflight, key)
+		sess.inflightMu.Unlock()
+		cancel()
+	}, true
+}
+
+// cancelRequest cancels an in-flight request, returning false if it is unknown
+func (sess *session) cancelRequest(id interface{}) bool {
+	sess.inflightMu.Lock()
+	cancel, ok := sess.inflight[requestKey(id)]
+	sess.inflightMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// cancelAll cancels every in-flight request of the session
+func (sess *session) cancelAll() {
+	sess.inflightMu.Lock()
+	defer sess.inflightMu.Unlock()
+	for _, cancel := range sess.inflight {
+		cancel()
+	}
+}
+
+// close ends the session, cancelling its in-flight requests and stopping
+// its resource watchers
+func (sess *session) close() {
+	sess.end()
+	sess.cancelAll()
+	sess.unsubscribeAll()
+}
+
+// request sends a server-initiated request through send and waits for the
+// client's response. If ctx is cancelled first, the client is told to stop.
+func (sess *session) request(ctx context.Context, send func(message interface{}), method string, params map[string]interface{}) (json.RawMessage, error) {
+	id := fmt.Sprintf("aeyewire-%d", sess.nextID.Add(1))
+	key := requestKey(id)
+	reply := make(chan *MCPRequest, 1)
+
+	sess.pendingMu.Lock()
+	sess.pending[key] = reply
+	sess.pendingMu.Unlock()
+
+	defer func() {
+		sess.pendingMu.Lock()
+		delete(sess.pending, key)
+		sess.pendingMu.Unlock()
+	}()
+
+	send(MCPRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+
+	select {
+	case response := <-reply:
+		if response.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s", method, response.Error.Message)
+		}
+		return response.Result, nil
+	case <-ctx.Done():
+		send(MCPNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/cancelled",
+			Params:  map[string]interface{}{"requestId": id},
+		})
+		return nil, ctx.Err()
+	}
+}
+
+// deliverResponse routes a client response to the server-initiated request
+// awaiting it. Responses to unknown requests, and repeated responses to a
+// request already answered, are dropped.
+func (sess *session) deliverResponse(response *MCPRequest) {
+	sess.pendingMu.Lock()
+	reply, ok := sess.pending[requestKey(response.ID)]
+	sess.pendingMu.Unlock()
+
+	if !ok {
+		return
+	}
+	select {
+	case reply <- response:
+	default:
+	}
+}
+
+type notifierKey struct{}
+
+// withNotifier returns a context whose request-related notifications, such
+// as progress, are delivered through send
+func withNotifier(ctx context.Context, send func(message interface{})) context.Context {
+	return context.WithValue(ctx, notifierKey{}, send)
+}
+
+// notifierFrom returns the notification sender of a request context. Without
+// one, notifications are dropped.
+func notifierFrom(ctx context.Context) func(message interface{}) {
+	if send, ok := ctx.Value(notifierKey{}).(func(message interface{})); ok {
+		return send
+	}
+	return func(message interface{}) {}
+}
+
+type sessionKey struct{}
+
+// withSession returns a context carrying the session a request belongs to
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sess)
+}
+
+// sessionFrom returns the session of a request context. Outside a request,
+// a fresh uninitialized session stands in.
+func sessionFrom(ctx context.Context) *session {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return sess
+	}
+	return newSession("", nil)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackRequestRejectsDuplicateID(t *testing.T) {
+	sess := newSession("test", nil)
+
+	ctx, done, ok := sess.trackRequest(float64(1))
+	if !ok {
+		t.Fatal("expected the first request to be tracked")
+	}
+	if _, _, ok := sess.trackRequest(float64(1)); ok {
+		t.Fatal("expected an ID already in flight to be rejected")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("the duplicate cancelled the request in flight")
+	}
+
+	// A request reusing the ID is answered with an error, not run
+	reply := NewMCPServer().process(sess, []MCPRequest{{JSONRPC: "2.0", ID: float64(1), Method: "ping"}}, false, nil)()
+	if response, _ := reply.(*MCPResponse); response == nil || response.Error == nil || response.Error.Code != -32600 {
+		t.Errorf("expected a -32600 error for the duplicate, got %+v", reply)
+	}
+
+	done()
+	if _, done, ok := sess.trackRequest(float64(1)); !ok {
+		t.Error("expected the ID to be reusable once the request completed")
+	} else {
+		done()
+	}
+}
+
+func TestDeliverResponseDoesNotBlock(t *testing.T) {
+	sess := newSession("test", nil)
+	reply := make(chan *MCPRequest, 1)
+	sess.pending[requestKey("aeyewire-1")] = reply
+
+	delivered := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			sess.deliverResponse(&MCPRequest{JSONRPC: "2.0", ID: "aeyewire-1"})
+		}
+		close(delivered)
+	}()
+
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("repeated responses blocked deliverResponse")
+	}
+	if len(reply) != 1 {
+		t.Errorf("expected the first response to be kept, got %d", len(reply))
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// FILE_REPORT_URI_PREFIX addresses the latest report of a workspace
+	// file by its absolute path
+	FILE_REPORT_URI_PREFIX = "aeyewire://files/"
+
+	// FILE_WATCH_INTERVAL is how often subscribed files are checked for
+	// changes
+	FILE_WATCH_INTERVAL = 2 * time.Second
+)
+
+// fileReportURI returns the resource URI of the latest report for path
+func fileReportURI(path string) string {
+	return FILE_REPORT_URI_PREFIX + strings.TrimPrefix(filepath.ToSlash(path), "/")
+}
+
+// filePathFromURI returns the file path addressed by a file report URI
+func filePathFromURI(uri string) string {
+	path := filepath.FromSlash(strings.TrimPrefix(uri, FILE_REPORT_URI_PREFIX))
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return string(filepath.Separator) + path
+}
+
+// analyzeWorkspaceFile reads the file at path, analyzes it with the analyzer
+// for its language and stores the report as the file's latest
+func (s *MCPServer) analyzeWorkspaceFile(ctx context.Context, path string) (*storedReport, error) {
+	code, err := readSourceFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report, mcpErr := s.analyzeAndStore(ctx, "", code, path, path)
+	if mcpErr != nil {
+		return nil, fmt.Errorf("%s", mcpErr.Message)
+	}
+	return report, nil
+}
+
+// handleResourcesSubscribe handles resources/subscribe request. Only file
+// reports change over time, so only they can be subscribed to.
+func (s *MCPServer) handleResourcesSubscribe(ctx context.Context, sess *session, request *MCPRequest) (interface{}, *MCPError) {
+	uri, _ := request.Params["uri"].(string)
+	if !strings.HasPrefix(uri, FILE_REPORT_URI_PREFIX) {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Cannot subscribe to %s: only %s resources change", uri, FILE_REPORT_URI_PREFIX)}
+	}
+
+	path, mcpErr := s.workspacePath(ctx, sess, filePathFromURI(uri))
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, &MCPError{Code: -32002, Message: fmt.Sprintf("Resource not found: %s", uri)}
+	}
+
+	watchCtx, cancel := context.WithCancel(sess.ctx)
+	sess.subscribe(uri, cancel)
+	go s.watchFile(watchCtx, sess, uri, path, info)
+
+	return map[string]interface{}{}, nil
+}
+
+// handleResourcesUnsubscribe handles resources/unsubscribe request
+func (s *MCPServer) handleResourcesUnsubscribe(sess *session, request *MCPRequest) (interface{}, *MCPError) {
+	uri, _ := request.Params["uri"].(string)
+	if uri == "" {
+		return nil, &MCPError{Code: -32602, Message: "Missing or invalid 'uri' parameter"}
+	}
+
+	sess.unsubscribe(uri)
+	return map[string]interface{}{}, nil
+}
+
+// watchFile polls a subscribed file until ctx is cancelled, which happens on
+// unsubscribe or when the session ends. Each time the file changes it is
+// re-analyzed, within the server's concurrency limit like any request, and
+// the client is sent notifications/resources/updated.
+func (s *MCPServer) watchFile(ctx context.Context, sess *session, uri string, path string, last os.FileInfo) {
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	// Analyses run with the session's notifier, sampler and logger, as a
+	// request of that session would
+	analysisCtx := s.sessionContext(withNotifier(ctx, sess.notify), sess)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || (info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+			continue
+		}
+		last = info
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		_, err = s.analyzeWorkspaceFile(analysisCtx, path)
+		<-s.slots
+
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Error re-analyzing %s: %v\n", path, err)
+			}
+			continue
+		}
+
+		sess.notify(MCPNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/resources/updated",
+			Params:  map[string]interface{}{"uri": uri},
+		})
+	}
+}
+
+// subscribe records the watcher of uri, replacing any previous one
+func (sess *session) subscribe(uri string, cancel context.CancelFunc) {
+	sess.subscriptionsMu.Lock()
+	defer sess.subscriptionsMu.Unlock()
+
+	if previous, ok := sess.subscriptions[uri]; ok {
+		previous()
+	}
+	sess.subscriptions[uri] = cancel
+}
+
+// unsubscribe stops the watcher of uri, if any
+func (sess *session) unsubscribe(uri string) {
+	sess.subscriptionsMu.Lock()
+	defer sess.subscriptionsMu.Unlock()
+
+	if cancel, ok := sess.subscriptions[uri]; ok {
+		cancel()
+		delete(sess.subscriptions, uri)
+	}
+}
+
+// unsubscribeAll stops every watcher of the session when it ends
+func (sess *session) unsubscribeAll() {
+	sess.subscriptionsMu.Lock()
+	defer sess.subscriptionsMu.Unlock()
+
+	for uri, cancel := range sess.subscriptions {
+		cancel()
+		delete(sess.subscriptions, uri)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// touch rewrites path with code and moves its modification time forward, so
+// the change is seen even on file systems with coarse timestamps
+func touch(t *testing.T, path string, code string, offset time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(offset)
+	os.Chtimes(path, modTime, modTime)
+}
+
+func TestResourceSubscription(t *testing.T) {
+	newFakeLLM(t, `[{"title":"XXE","severity":"HIGH","line_number":1}]`)
+	workspace := newScanWorkspace(t, "A.java")
+	path := filepath.Join(resolveExisting(workspace), "A.java")
+	uri := fileReportURI(path)
+
+	server := NewMCPServer()
+	server.watchInterval = 10 * time.Millisecond
+	client := startStdio(t, server)
+	client.initialize(`{}`)
+
+	client.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":%q}}`, uri))
+	if response := client.nextIgnoringLogs(); response["error"] != nil {
+		t.Fatalf("resources/subscribe failed: %s", response["error"])
+	}
+
+	touch(t, path, "public class A { void parse() {} }", time.Minute)
+
+	notification := client.nextIgnoringLogs()
+	var params struct {
+		URI string `json:"uri"`
+	}
+	json.Unmarshal(notification["params"], &params)
+	if string(notification["method"]) != `"notifications/resources/updated"` || params.URI != uri {
+		t.Fatalf("expected an update notification for %s, got %v", uri, notification)
+	}
+
+	if report := server.reports.latestForFile("stdio", path); report == nil || len(report.Result.Issues) != 1 {
+		t.Errorf("expected the re-analyzed report to be stored, got %+v", report)
+	}
+
+	client.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":%q}}`, uri))
+	client.nextIgnoringLogs()
+
+	// After unsubscribing, changes produce no notification; the next message
+	// answers the ping
+	touch(t, path, "public class A {}", 2*time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	client.send(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	if response := client.nextIgnoringLogs(); string(response["id"]) != "3" {
+		t.Errorf("expected no notification after unsubscribe, got %v", response)
+	}
+}
+
+func TestReadFileReport(t *testing.T) {
+	newFakeLLM(t, `[]`)
+	workspace := newScanWorkspace(t, "A.java")
+	path := filepath.Join(resolveExisting(workspace), "A.java")
+	server := NewMCPServer()
+
+	// The first read analyzes the file; later reads return the stored report
+	for i := 0; i < 2; i++ {
+		response := callMethod(t, server, "resources/read", map[string]interface{}{"uri": fileReportURI(path)})
+		if response.Error != nil {
+			t.Fatalf("resources/read failed: %s", response.Error.Message)
+		}
+	}
+	if reports := server.reports.list("test"); len(reports) != 1 || reports[0].SourcePath != path {
+		t.Errorf("expected a single stored report for %s, got %d", path, len(reports))
+	}
+
+	response := callMethod(t, server, "resources/read", map[string]interface{}{"uri": fileReportURI(filepath.Join(path, "..", "..", "B.java"))})
+	if response.Error == nil {
+		t.Error("expected a file outside the workspace to be rejected")
+	}
+
+	response = callMethod(t, server, "resources/subscribe", map[string]interface{}{"uri": RULES_URI_PREFIX + "java"})
+	if response.Error == nil {
+		t.Error("expected subscribing to a static resource to be rejected")
+	}
+}
+
+func TestWatchFileSharesSlots(t *testing.T) {
+	var analyses atomic.Int32
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		analyses.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": "[]"}},
+			},
+		})
+	}))
+	defer llm.Close()
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+
+	workspace := newScanWorkspace(t, "A.java")
+	path := filepath.Join(resolveExisting(workspace), "A.java")
+
+	server := NewMCPServer()
+	server.watchInterval = 10 * time.Millisecond
+	sess := newTestSession()
+
+	// Occupy every slot, as long-running requests would
+	for i := 0; i < cap(server.slots); i++ {
+		server.slots <- struct{}{}
+	}
+
+	request := &MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/subscribe", Params: map[string]interface{}{"uri": fileReportURI(path)}}
+	if response := server.handleRequest(context.Background(), sess, request); response.Error != nil {
+		t.Fatalf("resources/subscribe failed: %s", response.Error.Message)
+	}
+
+	touch(t, path, "public class A { void parse() {} }", time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	if n := analyses.Load(); n != 0 {
+		t.Fatalf("the watcher analyzed the file %d time(s) without a free slot", n)
+	}
+
+	<-server.slots
+	deadline := time.Now().Add(5 * time.Second)
+	for analyses.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the watcher did not analyze the file once a slot was free")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Closing the session stops its watchers
+	sess.close()
+	touch(t, path, "public class A {}", 2*time.Minute)
+	time.Sleep(100 * time.Millisecond)
+	if n := analyses.Load(); n != 1 {
+		t.Errorf("expected no analysis after the session closed, got %d in total", n)
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+	"github.com/emware/aeyewire-mcp/src/services"
+)
+
+// MAX_THREAT_MODEL_SIZE caps the total size of the sources sent to the LLM
+// for one threat model
+const MAX_THREAT_MODEL_SIZE = 256 * 1024
+
+// GENERATE_THREAT_MODEL_PROMPT asks the LLM for the architecture and STRIDE
+// threats of the code as JSON, which is rendered as markdown and Mermaid
+const GENERATE_THREAT_MODEL_PROMPT = `Build a STRIDE threat model of the application code below.
+
+Identify:
+- Components: the processes in the code (services, controllers, handlers, jobs) and the external entities they talk to (users, browsers, third-party APIs).
+- Data stores: databases, caches, files, queues and client-side storage the code reads or writes.
+- Trust boundaries: groups of components and data stores that trust each other, such as the internet, the application server or the database tier.
+- Entry points: where untrusted data enters, such as HTTP routes, message consumers, file readers and command-line arguments.
+- Data flows between components and data stores.
+- Threats for each STRIDE category (Spoofing, Tampering, Repudiation, Information Disclosure, Denial of Service, Elevation of Privilege) that apply to a component, data store or data flow. For each, name the mitigation and whether the code already has it: "present" when the code implements it, "partial" when it is incomplete, "missing" otherwise. Quote the file and line that shows a present or partial mitigation.
+
+Base everything on the code shown; do not invent components it does not reference.
+
+Return a single JSON object with this structure:
+{
+  "components": [{"id": "api", "name": "Orders API", "type": "process|external_entity", "file": "path", "description": "role"}],
+  "data_stores": [{"id": "db", "name": "Orders database", "data": "what it holds", "file": "path"}],
+  "trust_boundaries": [{"id": "server", "name": "Application server", "members": ["api", "db"]}],
+  "entry_points": [{"name": "POST /orders", "component": "api", "file": "path", "line_number": 0, "description": "input accepted"}],
+  "data_flows": [{"from": "user", "to": "api", "data": "order details"}],
+  "threats": [{"category": "Tampering", "target": "api", "threat": "description", "mitigation": "control", "status": "present|partial|missing", "evidence": "path:line"}]
+}`
+
+// STRIDE_CATEGORIES are the STRIDE threat categories, in table order
+var STRIDE_CATEGORIES = []string{
+	"Spoofing",
+	"Tampering",
+	"Repudiation",
+	"Information Disclosure",
+	"Denial of Service",
+	"Elevation of Privilege",
+}
+
+// threatComponent is a process or external entity of a threat model
+type threatComponent struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	File        string `json:"file,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// dataStore is where a threat model's components keep data
+type dataStore struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Data string `json:"data,omitempty"`
+	File string `json:"file,omitempty"`
+}
+
+// trustBoundary groups components and data stores that trust each other
+type trustBoundary struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// entryPoint is where untrusted data enters a component
+type entryPoint struct {
+	Name        string `json:"name"`
+	Component   string `json:"component"`
+	File        string `json:"file,omitempty"`
+	LineNumber  int    `json:"line_number,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// dataFlow is data moving between two components or data stores
+type dataFlow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Data string `json:"data,omitempty"`
+}
+
+// threat is one row of the STRIDE table
+type threat struct {
+	Category   string `json:"category"`
+	Target     string `json:"target"`
+	Threat     string `json:"threat"`
+	Mitigation string `json:"mitigation"`
+	Status     string `json:"status"`
+	Evidence   string `json:"evidence,omitempty"`
+}
+
+// threatModel is the result of generate_threat_model
+type threatModel struct {
+	Files           []string          `json:"files"`
+	Components      []threatComponent `json:"components"`
+	DataStores      []dataStore       `json:"data_stores"`
+	TrustBoundaries []trustBoundary   `json:"trust_boundaries"`
+	EntryPoints     []entryPoint      `json:"entry_points"`
+	DataFlows       []dataFlow        `json:"data_flows"`
+	Threats         []threat          `json:"threats"`
+	Diagram         string            `json:"diagram"`
+	Provider        string            `json:"provider,omitempty"`
+}
+
+// threatSource is a source file submitted for threat modeling
+type threatSource struct {
+	Path     string
+	Language models.LanguageType
+	Code     string
+}
+
+// generateThreatModel asks the LLM for a threat model of sources
+func (s *MCPServer) generateThreatModel(ctx context.Context, sources []threatSource) (*threatModel, error) {
+	var code strings.Builder
+	for _, source := range sources {
+		code.WriteString(fmt.Sprintf("File: %s (%s)\n%s\n\n", source.Path, source.Language, codeBlock(source.Code)))
+	}
+
+	messages := []services.Message{
+		{
+			Role:    "system",
+			Content: "You are a security architect who threat models applications with STRIDE. You answer with JSON only.",
+		},
+		{
+			Role:    "user",
+			Content: fmt.Sprintf("%s\n\nThe code follows.\n\n%s", GENERATE_THREAT_MODEL_PROMPT, code.String()),
+		},
+	}
+
+	reply, provider, err := s.llmService.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := parseThreatModel(reply)
+	if err != nil {
+		services.Log(ctx, services.LOG_WARNING, "threat_model", "Could not parse threat model from LLM response: %v. Response starts with: %.200s", err, reply)
+		return nil, fmt.Errorf("failed to parse threat model: %w", err)
+	}
+	for _, source := range sources {
+		model.Files = append(model.Files, source.Path)
+	}
+	model.Diagram = mermaidDiagram(model)
+	model.Provider = provider
+	return model, nil
+}
+
+// parseThreatModel decodes the JSON object of an LLM reply and normalizes
+// its STRIDE categories and mitigation statuses
+func parseThreatModel(reply string) (*threatModel, error) {
+	text := extractCodeBlock(reply)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		text = text[start : end+1]
+	}
+
+	model := &threatModel{}
+	if err := json.Unmarshal([]byte(text), model); err != nil {
+		return nil, err
+	}
+
+	// Keep empty lists as [] rather than null in JSON output
+	if model.Components == nil {
+		model.Components = []threatComponent{}
+	}
+	if model.DataStores == nil {
+		model.DataStores = []dataStore{}
+	}
+	if model.TrustBoundaries == nil {
+		model.TrustBoundaries = []trustBoundary{}
+	}
+	if model.EntryPoints == nil {
+		model.EntryPoints = []entryPoint{}
+	}
+	if model.DataFlows == nil {
+		model.DataFlows = []dataFlow{}
+	}
+	if model.Threats == nil {
+		model.Threats = []threat{}
+	}
+	model.Files = []string{}
+
+	for i := range model.Threats {
+		model.Threats[i].Category = strideCategory(model.Threats[i].Category)
+		model.Threats[i].Status = mitigationStatus(model.Threats[i].Status)
+	}
+	sort.SliceStable(model.Threats, func(i, j int) bool {
+		return strideIndex(model.Threats[i].Category) < strideIndex(model.Threats[j].Category)
+	})
+
+	return model, nil
+}
+
+// strideCategory returns the STRIDE category named, or abbreviated to its
+// initial, by category, or category itself when it is none of them
+func strideCategory(category string) string {
+	category = strings.TrimSpace(category)
+	for _, name := range STRIDE_CATEGORIES {
+		if strings.EqualFold(category, name) || strings.EqualFold(category, name[:1]) {
+			return name
+		}
+	}
+	return category
+}
+
+// strideIndex orders categories as in STRIDE, unknown ones last
+func strideIndex(category string) int {
+	for i, name := range STRIDE_CATEGORIES {
+		if category == name {
+			return i
+		}
+	}
+	return len(STRIDE_CATEGORIES)
+}
+
+// mitigationStatus normalizes a mitigation status to present, partial or
+// missing
+func mitigationStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "present", "implemented", "mitigated", "yes":
+		return "present"
+	case "partial", "partially present", "incomplete":
+		return "partial"
+	default:
+		return "missing"
+	}
+}
+
+// mermaidDiagram draws the data flows of a model as a Mermaid flowchart,
+// with trust boundaries as subgraphs, processes as rounded boxes, external
+// entities as boxes and data stores as cylinders
+func mermaidDiagram(model *threatModel) string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+
+	nodes := map[string]string{}
+	order := []string{}
+	addNode := func(id string, node string) {
+		if _, ok := nodes[id]; !ok {
+			order = append(order, id)
+		}
+		nodes[id] = node
+	}
+	for _, component := range model.Components {
+		if component.Type == "external_entity" {
+			addNode(component.ID, fmt.Sprintf("%s[\"%s\"]", mermaidID(component.ID), mermaidLabel(component.Name)))
+		} else {
+			addNode(component.ID, fmt.Sprintf("%s(\"%s\")", mermaidID(component.ID), mermaidLabel(component.Name)))
+		}
+	}
+	for _, store := range model.DataStores {
+		addNode(store.ID, fmt.Sprintf("%s[(\"%s\")]", mermaidID(store.ID), mermaidLabel(store.Name)))
+	}
+	// Flows may reference endpoints the LLM did not list
+	for _, flow := range model.DataFlows {
+		for _, id := range []string{flow.From, flow.To} {
+			if _, ok := nodes[id]; !ok {
+				addNode(id, fmt.Sprintf("%s[\"%s\"]", mermaidID(id), mermaidLabel(id)))
+			}
+		}
+	}
+
+	// A node is drawn once, inside the first boundary listing it
+	drawn := map[string]bool{}
+	for _, boundary := range model.TrustBoundaries {
+		sb.WriteString(fmt.Sprintf("  subgraph %s[\"%s\"]\n", mermaidID("boundary_"+boundary.ID), mermaidLabel(boundary.Name)))
+		for _, member := range boundary.Members {
+			if node, ok := nodes[member]; ok && !drawn[member] {
+				drawn[member] = true
+				sb.WriteString("    " + node + "\n")
+			}
+		}
+		sb.WriteString("  end\n")
+	}
+	for _, id := range order {
+		if !drawn[id] {
+			sb.WriteString("  " + nodes[id] + "\n")
+		}
+	}
+
+	for _, flow := range model.DataFlows {
+		if flow.Data == "" {
+			sb.WriteString(fmt.Sprintf("  %s --> %s\n", mermaidID(flow.From), mermaidID(flow.To)))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s -->|\"%s\"| %s\n", mermaidID(flow.From), mermaidLabel(flow.Data), mermaidID(flow.To)))
+		}
+	}
+
+	return sb.String()
+}
+
+// mermaidID turns an identifier into a Mermaid node ID. The prefix keeps
+// IDs such as "end" from clashing with Mermaid keywords.
+func mermaidID(id string) string {
+	var sb strings.Builder
+	sb.WriteString("n_")
+	for _, r := range id {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// mermaidLabel escapes text for a quoted Mermaid label
+func mermaidLabel(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "\"", "#quot;")
+}
+
+// tableCell escapes text for a markdown table cell
+func tableCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+// formatThreatModel formats a threat model as markdown
+func formatThreatModel(model *threatModel) string {
+	var sb strings.Builder
+
+	missing := 0
+	for _, threat := range model.Threats {
+		if threat.Status != "present" {
+			missing++
+		}
+	}
+
+	sb.WriteString("# Threat Model\n\n")
+	sb.WriteString(fmt.Sprintf("**Files**: %s\n\n", strings.Join(model.Files, ", ")))
+	if model.Provider != "" {
+		sb.WriteString(fmt.Sprintf("**Provider**: %s\n\n", model.Provider))
+	}
+	sb.WriteString(fmt.Sprintf("## Summary\n\n%d component(s), %d data store(s), %d trust boundary(ies) and %d entry point(s). %d threat(s), %d without a complete mitigation.\n\n",
+		len(model.Components), len(model.DataStores), len(model.TrustBoundaries), len(model.EntryPoints), len(model.Threats), missing))
+
+	if len(model.Components) > 0 {
+		sb.WriteString("## Components\n\n| Component | Type | File | Description |\n|---|---|---|---|\n")
+		for _, component := range model.Components {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+				tableCell(component.Name), tableCell(component.Type), tableCell(component.File), tableCell(component.Description)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(model.DataStores) > 0 {
+		sb.WriteString("## Data Stores\n\n| Data Store | Data | File |\n|---|---|---|\n")
+		for _, store := range model.DataStores {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", tableCell(store.Name), tableCell(store.Data), tableCell(store.File)))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(model.TrustBoundaries) > 0 {
+		sb.WriteString("## Trust Boundaries\n\n")
+		for _, boundary := range model.TrustBoundaries {
+			sb.WriteString(fmt.Sprintf("- **%s**: %s\n", boundary.Name, strings.Join(boundary.Members, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(model.EntryPoints) > 0 {
+		sb.WriteString("## Entry Points\n\n| Entry Point | Component | Location | Description |\n|---|---|---|---|\n")
+		for _, entry := range model.EntryPoints {
+			location := entry.File
+			if entry.LineNumber > 0 {
+				location = fmt.Sprintf("%s:%d", entry.File, entry.LineNumber)
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n",
+				tableCell(entry.Name), tableCell(entry.Component), tableCell(location), tableCell(entry.Description)))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("## Data Flow Diagram\n\n```mermaid\n")
+	sb.WriteString(model.Diagram)
+	sb.WriteString("```\n\n")
+
+	sb.WriteString("## STRIDE Threats\n\n")
+	if len(model.Threats) == 0 {
+		sb.WriteString("No threats identified.\n")
+		return sb.String()
+	}
+	sb.WriteString("| Category | Target | Threat | Mitigation | Status | Evidence |\n|---|---|---|---|---|---|\n")
+	for _, threat := range model.Threats {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			tableCell(threat.Category), tableCell(threat.Target), tableCell(threat.Threat),
+			tableCell(threat.Mitigation), strings.ToUpper(threat.Status), tableCell(threat.Evidence)))
+	}
+
+	return sb.String()
+}
+
+// handleGenerateThreatModel handles the generate_threat_model tool
+func (s *MCPServer) handleGenerateThreatModel(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	languageStr, _ := args["language"].(string)
+
+	sources := []threatSource{}
+	size := 0
+	list, _ := args["files"].([]interface{})
+	for _, item := range list {
+		entry := item.(map[string]interface{})
+		path, _ := entry["path"].(string)
+		code, ok := entry["content"].(string)
+		if !ok {
+			resolved, mcpErr := s.workspacePath(ctx, sessionFrom(ctx), path)
+			if mcpErr != nil {
+				return nil, mcpErr
+			}
+			var err error
+			if code, err = readSourceFile(resolved); err != nil {
+				return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("Cannot read %s: %v", path, err)}
+			}
+		}
+		code = normalizeLineEndings(code)
+
+		language, _, mcpErr := s.resolveAnalyzer(ctx, languageStr, code, path)
+		if mcpErr != nil {
+			return nil, &MCPError{Code: mcpErr.Code, Message: fmt.Sprintf("%s: %s", path, mcpErr.Message)}
+		}
+
+		size += len(code)
+		sources = append(sources, threatSource{Path: path, Language: language, Code: code})
+	}
+	if len(sources) == 0 {
+		return nil, &MCPError{Code: -32602, Message: "Missing 'files' parameter"}
+	}
+	if size > MAX_THREAT_MODEL_SIZE {
+		return nil, &MCPError{Code: -32602, Message: fmt.Sprintf("The files total %d bytes, more than the %d a threat model can cover; pass fewer files", size, MAX_THREAT_MODEL_SIZE)}
+	}
+
+	model, err := s.generateThreatModel(ctx, sources)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Threat modeling failed: %v", err)}
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatThreatModel(model),
+			},
+		},
+		"structuredContent": model,
+	}
+	return response, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testThreatModel = `{
+  "components": [
+    {"id": "user", "name": "Browser \"client\"", "type": "external_entity"},
+    {"id": "api", "name": "Orders API", "type": "process", "file": "OrderController.java"}
+  ],
+  "data_stores": [{"id": "db", "name": "Orders DB", "data": "orders | payments"}],
+  "trust_boundaries": [{"id": "server", "name": "Server", "members": ["api", "db"]}],
+  "entry_points": [{"name": "POST /orders", "component": "api", "file": "OrderController.java", "line_number": 12}],
+  "data_flows": [{"from": "user", "to": "api", "data": "order"}, {"from": "api", "to": "db"}],
+  "threats": [
+    {"category": "Elevation of Privilege", "target": "api", "threat": "Any user can cancel any order", "mitigation": "Ownership check", "status": "missing"},
+    {"category": "I", "target": "db", "threat": "Payment data readable", "mitigation": "Column encryption", "status": "Implemented", "evidence": "OrderRepository.java:30"},
+    {"category": "spoofing", "target": "api", "threat": "Forged sessions", "mitigation": "Signed tokens", "status": "partial"}
+  ]
+}`
+
+func TestParseThreatModel(t *testing.T) {
+	model, err := parseThreatModel("Here is the model:\n```json\n" + testThreatModel + "\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories := []string{}
+	statuses := []string{}
+	for _, threat := range model.Threats {
+		categories = append(categories, threat.Category)
+		statuses = append(statuses, threat.Status)
+	}
+	if strings.Join(categories, ",") != "Spoofing,Information Disclosure,Elevation of Privilege" {
+		t.Errorf("expected threats in STRIDE order, got %v", categories)
+	}
+	if strings.Join(statuses, ",") != "partial,present,missing" {
+		t.Errorf("unexpected statuses %v", statuses)
+	}
+
+	if _, err := parseThreatModel("I cannot model this code."); err == nil {
+		t.Error("expected a reply without JSON to be rejected")
+	}
+}
+
+func TestMermaidDiagram(t *testing.T) {
+	model, _ := parseThreatModel(testThreatModel)
+	diagram := mermaidDiagram(model)
+
+	for _, expected := range []string{
+		"flowchart LR\n",
+		"  subgraph n_boundary_server[\"Server\"]\n    n_api(\"Orders API\")\n    n_db[(\"Orders DB\")]\n  end\n",
+		"  n_user[\"Browser #quot;client#quot;\"]\n",
+		"  n_user -->|\"order\"| n_api\n",
+		"  n_api --> n_db\n",
+	} {
+		if !strings.Contains(diagram, expected) {
+			t.Errorf("expected the diagram to contain %q, got:\n%s", expected, diagram)
+		}
+	}
+	if strings.Count(diagram, "n_api(") != 1 {
+		t.Errorf("expected each node to be declared once, got:\n%s", diagram)
+	}
+}
+
+func TestGenerateThreatModel(t *testing.T) {
+	newFakeLLM(t, testThreatModel)
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "generate_threat_model",
+		"arguments": map[string]interface{}{
+			"files": []interface{}{
+				map[string]interface{}{"path": "OrderController.java", "content": "class OrderController {}\n"},
+			},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("generate_threat_model failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		StructuredContent threatModel `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	markdown := result.Content[0].Text
+	for _, expected := range []string{
+		"**Files**: OrderController.java",
+		"3 threat(s), 2 without a complete mitigation",
+		"| Orders DB | orders \\| payments |",
+		"| POST /orders | api | OrderController.java:12 |",
+		"```mermaid\nflowchart LR\n",
+		"| Elevation of Privilege | api | Any user can cancel any order | Ownership check | MISSING |",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Errorf("expected the report to contain %q, got:\n%s", expected, markdown)
+		}
+	}
+	if result.StructuredContent.Diagram == "" || len(result.StructuredContent.Threats) != 3 {
+		t.Errorf("unexpected structured content: %s", jsonData)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name":      "generate_threat_model",
+		"arguments": map[string]interface{}{"files": []interface{}{}},
+	})
+	if response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("expected a call without files to be rejected, got %+v", response)
+	}
+
+	response = callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "generate_threat_model",
+		"arguments": map[string]interface{}{
+			"files": []interface{}{
+				map[string]interface{}{"path": "OrderController.java", "content": "class OrderController {}\n"},
+				map[string]interface{}{"path": "notes.txt", "content": "Orders are stored for a year\n"},
+			},
+		},
+	})
+	if response.Error == nil || response.Error.Code != -32602 || !strings.Contains(response.Error.Message, "notes.txt: Unsupported language") {
+		t.Errorf("expected a file in an unsupported language to be rejected, got %+v", response.Error)
+	}
+}
@@ -0,0 +1,650 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TOOLS_PAGE_SIZE is the number of tools returned per tools/list page
+const TOOLS_PAGE_SIZE = 20
+
+// toolHandler runs a tool with arguments that already passed validation
+type toolHandler func(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError)
+
+// toolDefinition describes a tool as advertised by tools/list, along with
+// the handler that tools/call dispatches to
+type toolDefinition struct {
+	Name         string
+	Description  string
+	InputSchema  map[string]interface{}
+	OutputSchema map[string]interface{}
+	// Annotations are behaviour hints such as readOnlyHint and idempotentHint
+	Annotations map[string]interface{}
+	Handler     toolHandler
+}
+
+// ToolRegistry holds the tools exposed by the server in registration order
+type ToolRegistry struct {
+	tools    []*toolDefinition
+	byName   map[string]*toolDefinition
+	pageSize int
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		byName:   make(map[string]*toolDefinition),
+		pageSize: TOOLS_PAGE_SIZE,
+	}
+}
+
+// Register adds a tool. Registering the same name twice is a programming
+// error and panics.
+func (r *ToolRegistry) Register(tool toolDefinition) {
+	if _, exists := r.byName[tool.Name]; exists {
+		panic(fmt.Sprintf("tool %s registered twice", tool.Name))
+	}
+	r.tools = append(r.tools, &tool)
+	r.byName[tool.Name] = &tool
+}
+
+// Get returns the tool registered under name
+func (r *ToolRegistry) Get(name string) (*toolDefinition, bool) {
+	tool, ok := r.byName[name]
+	return tool, ok
+}
+
+// Page returns the tools starting at cursor, and the cursor of the next page
+// or "" on the last page. An empty cursor starts at the first tool.
+func (r *ToolRegistry) Page(cursor string) ([]*toolDefinition, string, error) {
+	start := 0
+	if cursor != "" {
+		decoded, err := base64.StdEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+		start, err = strconv.Atoi(string(decoded))
+		if err != nil || start < 0 || start > len(r.tools) {
+			return nil, "", fmt.Errorf("invalid cursor")
+		}
+	}
+
+	end := start + r.pageSize
+	if end >= len(r.tools) {
+		return r.tools[start:], "", nil
+	}
+	return r.tools[start:end], base64.StdEncoding.EncodeToString([]byte(strconv.Itoa(end))), nil
+}
+
+// toMap returns the tools/list entry of the tool
+func (tool *toolDefinition) toMap() map[string]interface{} {
+	entry := map[string]interface{}{
+		"name":        tool.Name,
+		"description": tool.Description,
+		"inputSchema": tool.InputSchema,
+	}
+	if tool.OutputSchema != nil {
+		entry["outputSchema"] = tool.OutputSchema
+	}
+	if tool.Annotations != nil {
+		entry["annotations"] = tool.Annotations
+	}
+	return entry
+}
+
+// registerTools declares the tools exposed by the server. Analyzers must be
+// registered first, since the language enum is built from them.
+func (s *MCPServer) registerTools() {
+	languages := s.languageNames()
+
+	s.tools.Register(toolDefinition{
+		Name:        "analyze_security",
+		Description: "Performs comprehensive security analysis on source code",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code": map[string]interface{}{
+					"type":        "string",
+					"description": "Source code to analyze",
+				},
+				"file_path": map[string]interface{}{
+					"type":        "string",
+					"description": "File path for context and language detection (optional)",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language (%s)", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"code"},
+		},
+		OutputSchema: analysisResultSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Analyze code security",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleAnalyzeSecurity,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "analyze_file",
+		Description: "Reads one or more source files from the workspace and performs security analysis on each",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "File to analyze, inside the workspace roots",
+				},
+				"paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Several files to analyze, inside the workspace roots",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of every file (%s); detected per file by default", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+		},
+		OutputSchema: fileResultsSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Analyze files",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleAnalyzeFile,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "analyze_project",
+		Description: "Analyzes every source file under a directory in parallel and returns an aggregated report with a severity summary and the riskiest files",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory to analyze, inside the workspace roots (defaults to the first root). .gitignore and .aeyewireignore files are honoured",
+				},
+			},
+		},
+		OutputSchema: projectReportSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Analyze project",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleAnalyzeProject,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "analyze_diff",
+		Description: "Reviews a unified diff for security issues, reporting only issues on added or modified lines with their new-file line numbers",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"diff": map[string]interface{}{
+					"type":        "string",
+					"description": "Unified diff, as produced by git diff or diff -u",
+				},
+				"files": map[string]interface{}{
+					"type":        "array",
+					"description": "Post-change contents of every file changed by the diff",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{
+								"type":        "string",
+								"description": "Path of the file as it appears in the diff, without the b/ prefix",
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "Contents of the file after the change",
+							},
+						},
+						"required": []string{"path", "content"},
+					},
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of every file (%s); detected per file by default", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"diff", "files"},
+		},
+		OutputSchema: fileResultsSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Analyze diff",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleAnalyzeDiff,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "scan_git_changes",
+		Description: "Uses git to find the files and hunks changed in the working tree, the index or a commit range, and reports security issues on the changed lines only",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Directory inside a git repository, inside the workspace roots (defaults to the first root); only changes under it are scanned",
+				},
+				"staged": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Scan the changes staged for commit instead of the working tree",
+				},
+				"range": map[string]interface{}{
+					"type":        "string",
+					"description": "Scan the commits of a <base>..<head> range instead of the working tree; issues are tagged with the commit that introduced them",
+				},
+			},
+		},
+		OutputSchema: fileResultsSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Scan git changes",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleScanGitChanges,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "explain_issue",
+		Description: "Explains one security finding in depth: an attack scenario with example malicious input, why the code is exploitable, the CWE background and how the remediation fixes it",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code": map[string]interface{}{
+					"type":        "string",
+					"description": "Source code the finding was reported in",
+				},
+				"issue": map[string]interface{}{
+					"type":        "object",
+					"description": "The finding to explain, as returned in an analysis result",
+					"properties": map[string]interface{}{
+						"title":       map[string]interface{}{"type": "string"},
+						"description": map[string]interface{}{"type": "string"},
+						"severity":    map[string]interface{}{"type": "string"},
+						"line_number": map[string]interface{}{"type": "integer"},
+					},
+					"required": []string{"title"},
+				},
+				"fingerprint": map[string]interface{}{
+					"type":        "string",
+					"description": "Fingerprint of a finding from a stored report, instead of the finding itself",
+				},
+				"file_path": map[string]interface{}{
+					"type":        "string",
+					"description": "File path for context and language detection",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of the code (%s)", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"code"},
+		},
+		OutputSchema: explanationSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Explain issue",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleExplainIssue,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "suggest_fix",
+		Description: "Asks the LLM for a minimal fix of one or more findings and returns it as a unified diff that is checked to apply cleanly, also as an embedded resource",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code": map[string]interface{}{
+					"type":        "string",
+					"description": "Source code to fix",
+				},
+				"issues": map[string]interface{}{
+					"type":        "array",
+					"description": "Findings to fix, as returned in an analysis result",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"title":       map[string]interface{}{"type": "string"},
+							"description": map[string]interface{}{"type": "string"},
+							"remediation": map[string]interface{}{"type": "string"},
+							"line_number": map[string]interface{}{"type": "integer"},
+						},
+						"required": []string{"title"},
+					},
+				},
+				"fingerprints": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Fingerprints of findings from stored reports, instead of the findings themselves",
+				},
+				"file_path": map[string]interface{}{
+					"type":        "string",
+					"description": "File path for the patch headers and language detection",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of the code (%s)", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"code"},
+		},
+		OutputSchema: fixSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Suggest fix",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleSuggestFix,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "verify_fix",
+		Description: "Re-analyzes original and patched code and reports which findings the patch resolved, which persist and which are new, matching findings by rule and location",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"original_code": map[string]interface{}{
+					"type":        "string",
+					"description": "Source code before the patch",
+				},
+				"patched_code": map[string]interface{}{
+					"type":        "string",
+					"description": "Source code after the patch",
+				},
+				"issues": map[string]interface{}{
+					"type":        "array",
+					"description": "Findings reported for the original code",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"rule_id":      map[string]interface{}{"type": "string"},
+							"title":        map[string]interface{}{"type": "string"},
+							"line_number":  map[string]interface{}{"type": "integer"},
+							"code_snippet": map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"title"},
+					},
+				},
+				"fingerprints": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Fingerprints of the original findings from stored reports, instead of the findings themselves",
+				},
+				"file_path": map[string]interface{}{
+					"type":        "string",
+					"description": "File path for context and language detection",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of the code (%s)", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"original_code", "patched_code"},
+		},
+		OutputSchema: fixVerificationSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Verify fix",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleVerifyFix,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "compare_reports",
+		Description: "Compares two analysis results, such as before and after a refactor or from two models, and reports new, fixed and unchanged findings matched by fingerprint",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"before": map[string]interface{}{
+					"type":        "object",
+					"description": "Earlier AnalysisResult, such as the structuredContent of analyze_security",
+				},
+				"after": map[string]interface{}{
+					"type":        "object",
+					"description": "Later AnalysisResult to compare with the earlier one",
+				},
+			},
+			"required": []string{"before", "after"},
+		},
+		OutputSchema: reportComparisonSchema(),
+		Annotations: map[string]interface{}{
+			"title":          "Compare reports",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: s.handleCompareReports,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "generate_threat_model",
+		Description: "Builds a STRIDE threat model of one or more source files: components, data stores, trust boundaries, entry points, a Mermaid data-flow diagram and threats with their mitigations present or missing",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"files": map[string]interface{}{
+					"type":        "array",
+					"description": "Source files to model together",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{
+								"type":        "string",
+								"description": "Path of the file, read from the workspace when content is omitted",
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "Contents of the file",
+							},
+						},
+						"required": []string{"path"},
+					},
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Programming language of every file (%s); detected per file by default", strings.Join(languages, ", ")),
+					"enum":        languages,
+				},
+			},
+			"required": []string{"files"},
+		},
+		OutputSchema: threatModelSchema(),
+		Annotations: map[string]interface{}{
+			"title":           "Generate threat model",
+			"readOnlyHint":    true,
+			"destructiveHint": false,
+			"idempotentHint":  false,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleGenerateThreatModel,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "list_rules",
+		Description: "Lists the security rules the analyzers check for, with their CWE IDs, OWASP category and default severity",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": "Only list the rules of this language",
+					"enum":        s.registeredLanguageNames(),
+				},
+			},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"rules": map[string]interface{}{"type": "array", "items": securityRuleSchema()},
+			},
+			"required": []string{"rules"},
+		},
+		Annotations: map[string]interface{}{
+			"title":          "List rules",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: s.handleListRules,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "get_rule",
+		Description: "Describes a security rule by the ID that findings carry as rule_id",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"rule_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Rule ID, such as java/sql-injection",
+				},
+			},
+			"required": []string{"rule_id"},
+		},
+		OutputSchema: securityRuleSchema(),
+		Annotations: map[string]interface{}{
+			"title":          "Get rule",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: s.handleGetRule,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "health_check",
+		Description: "Verifies service health and dependency availability",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+		Annotations: map[string]interface{}{
+			"title":          "Health check",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+			return s.handleHealthCheck()
+		},
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "list_supported_languages",
+		Description: "Lists all supported programming languages and their metadata",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+		Annotations: map[string]interface{}{
+			"title":          "List supported languages",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+			return s.handleListSupportedLanguages()
+		},
+	})
+
+	jobID := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"job_id": map[string]interface{}{"type": "string", "description": "Job ID returned by start_scan"}},
+		"required":   []string{"job_id"},
+	}
+
+	s.tools.Register(toolDefinition{
+		Name:        "start_scan",
+		Description: "Starts a background security scan of a file or directory and returns a job ID immediately",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "File or directory to scan, inside the workspace roots",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Annotations: map[string]interface{}{
+			"title":          "Start security scan",
+			"readOnlyHint":   true,
+			"idempotentHint": false,
+			"openWorldHint":  false,
+		},
+		Handler: s.handleStartScan,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "get_scan_status",
+		Description: "Reports the progress of a scan job: files done, issues found so far and estimated time remaining",
+		InputSchema: jobID,
+		Annotations: map[string]interface{}{
+			"title":         "Get scan status",
+			"readOnlyHint":  true,
+			"openWorldHint": false,
+		},
+		Handler: s.handleGetScanStatus,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "get_scan_result",
+		Description: "Returns the aggregated analysis results of a finished scan job",
+		InputSchema: jobID,
+		Annotations: map[string]interface{}{
+			"title":          "Get scan result",
+			"readOnlyHint":   true,
+			"idempotentHint": true,
+			"openWorldHint":  false,
+		},
+		Handler: s.handleGetScanResult,
+	})
+
+	s.tools.Register(toolDefinition{
+		Name:        "cancel_scan",
+		Description: "Stops a running scan job, keeping the results of files already analyzed",
+		InputSchema: jobID,
+		Annotations: map[string]interface{}{
+			"title":           "Cancel scan",
+			"readOnlyHint":    false,
+			"destructiveHint": false,
+			"idempotentHint":  true,
+			"openWorldHint":   false,
+		},
+		Handler: s.handleCancelScan,
+	})
+}
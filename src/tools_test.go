@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestToolsListPagination(t *testing.T) {
+	server := NewMCPServer()
+	server.tools.pageSize = 2
+
+	var names []string
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		response := callMethod(t, server, "tools/list", params)
+		if response.Error != nil {
+			t.Fatalf("tools/list failed: %s", response.Error.Message)
+		}
+
+		result := response.Result.(map[string]interface{})
+		for _, tool := range result["tools"].([]map[string]interface{}) {
+			names = append(names, tool["name"].(string))
+			if tool["annotations"] == nil {
+				t.Errorf("tool %s has no annotations", tool["name"])
+			}
+		}
+
+		next, ok := result["nextCursor"].(string)
+		if !ok {
+			break
+		}
+		cursor = next
+	}
+
+	if len(names) != len(server.tools.tools) || names[0] != "analyze_security" {
+		t.Errorf("expected every tool once in registration order, got %v", names)
+	}
+
+	response := callMethod(t, server, "tools/list", map[string]interface{}{"cursor": "not a cursor"})
+	if response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("expected invalid cursor to be rejected, got %+v", response)
+	}
+}
+
+func TestToolsCallValidatesArguments(t *testing.T) {
+	server := NewMCPServer()
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		expected  string
+	}{
+		{"missing code", map[string]interface{}{"language": "java"}, "missing required argument 'code'"},
+		{"wrong type", map[string]interface{}{"code": 42}, "'code' must be a string"},
+		{"unknown language", map[string]interface{}{"code": "x", "language": "cobol"}, "'language' must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := callMethod(t, server, "tools/call", map[string]interface{}{
+				"name":      "analyze_security",
+				"arguments": tt.arguments,
+			})
+			if response.Error == nil || response.Error.Code != -32602 {
+				t.Fatalf("expected invalid params, got %+v", response)
+			}
+			if !strings.Contains(response.Error.Message, tt.expected) {
+				t.Errorf("expected error containing %q, got %q", tt.expected, response.Error.Message)
+			}
+		})
+	}
+}
+
+func TestToolRegistryDispatch(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(toolDefinition{
+		Name: "echo",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"count": map[string]interface{}{"type": "integer"},
+				"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+			return args["count"], nil
+		},
+	})
+
+	tool, ok := registry.Get("echo")
+	if !ok {
+		t.Fatal("registered tool not found")
+	}
+
+	if err := validateArguments(tool.InputSchema, map[string]interface{}{"count": 1.5}); err == nil {
+		t.Error("expected a fractional count to be rejected")
+	}
+	if err := validateArguments(tool.InputSchema, map[string]interface{}{"tags": []interface{}{"a", 1.0}}); err == nil {
+		t.Error("expected a non-string tag to be rejected")
+	}
+	if err := validateArguments(tool.InputSchema, map[string]interface{}{"count": 3.0, "tags": []interface{}{"a"}}); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a duplicate tool to panic")
+		}
+	}()
+	registry.Register(toolDefinition{Name: "echo"})
+}
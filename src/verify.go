@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/emware/aeyewire-mcp/src/analyzers"
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// LOCATION_TOLERANCE is how many lines apart two findings of the same rule
+// may be, after accounting for lines the patch added or removed, and still
+// be considered the same finding
+const LOCATION_TOLERANCE = 3
+
+// fixVerification classifies findings after a patch
+type fixVerification struct {
+	// Resolved are the original findings absent from the patched code
+	Resolved []models.SecurityIssue `json:"resolved"`
+	// Persisting are the findings of the patched code matching an original
+	// finding
+	Persisting []models.SecurityIssue `json:"persisting"`
+	// New are the findings of the patched code found neither among the
+	// original findings nor in the original code
+	New      []models.SecurityIssue `json:"new"`
+	Verified bool                   `json:"verified"`
+}
+
+// lineMapper returns a function mapping a line of original to the line of
+// patched it became. Removed lines map to where they used to be. It fails
+// when the code is too long to diff.
+func lineMapper(original string, patched string) (func(int) int, error) {
+	a, _ := splitLines(original)
+	b, _ := splitLines(patched)
+	edits, err := diffLines(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make([]int, len(a)+1)
+	x, y := 0, 0
+	for _, edit := range edits {
+		switch edit.Op {
+		case ' ':
+			mapping[x] = y + 1
+			x++
+			y++
+		case '-':
+			mapping[x] = y + 1
+			x++
+		case '+':
+			y++
+		}
+	}
+
+	return func(line int) int {
+		if line <= 0 || line > len(a) {
+			return line
+		}
+		return mapping[line-1]
+	}, nil
+}
+
+// sameFinding reports whether two findings, before and after a patch, are
+// the same: same rule, and same location once mapped through the patch or
+// same flagged code. Findings without a line match on the rule alone.
+func sameFinding(before models.SecurityIssue, after models.SecurityIssue, mapLine func(int) int) bool {
+	if !analyzers.SameRule(before, after) {
+		return false
+	}
+	if before.LineNumber <= 0 || after.LineNumber <= 0 {
+		return true
+	}
+
+	distance := mapLine(before.LineNumber) - after.LineNumber
+	if distance >= -LOCATION_TOLERANCE && distance <= LOCATION_TOLERANCE {
+		return true
+	}
+	snippet := strings.Join(strings.Fields(before.CodeSnippet), " ")
+	return snippet != "" && snippet == strings.Join(strings.Fields(after.CodeSnippet), " ")
+}
+
+// matchFindings pairs each finding of before with at most one finding of
+// after, returning which findings of each were matched
+func matchFindings(before []models.SecurityIssue, after []models.SecurityIssue, mapLine func(int) int) (map[int]bool, map[int]bool) {
+	matchedBefore, matchedAfter := map[int]bool{}, map[int]bool{}
+	for i, issue := range before {
+		for j, candidate := range after {
+			if !matchedAfter[j] && sameFinding(issue, candidate, mapLine) {
+				matchedBefore[i], matchedAfter[j] = true, true
+				break
+			}
+		}
+	}
+	return matchedBefore, matchedAfter
+}
+
+// verifyFix re-analyzes the original and the patched code and classifies
+// findings against the original ones
+func (s *MCPServer) verifyFix(ctx context.Context, analyzer analyzers.SecurityAnalyzer, filePath string, original string, patched string, findings []models.SecurityIssue) (*fixVerification, error) {
+	mapLine, err := lineMapper(original, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := analyzer.Analyze(analyzers.ForUnit(ctx, 0, 2), original, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("analysis of the original code failed: %w", err)
+	}
+	after, err := analyzer.Analyze(analyzers.ForUnit(ctx, 1, 2), patched, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("analysis of the patched code failed: %w", err)
+	}
+
+	verification := &fixVerification{
+		Resolved:   []models.SecurityIssue{},
+		Persisting: []models.SecurityIssue{},
+		New:        []models.SecurityIssue{},
+	}
+
+	matchedFindings, persisting := matchFindings(findings, after.Issues, mapLine)
+	for i, issue := range findings {
+		if !matchedFindings[i] {
+			verification.Resolved = append(verification.Resolved, issue)
+		}
+	}
+
+	// Findings the re-run also reports in the original code were there
+	// before the patch, even if they were not among the findings passed in
+	_, preexisting := matchFindings(before.Issues, after.Issues, mapLine)
+	for j, issue := range after.Issues {
+		switch {
+		case persisting[j]:
+			verification.Persisting = append(verification.Persisting, issue)
+		case !preexisting[j]:
+			verification.New = append(verification.New, issue)
+		}
+	}
+
+	verification.Verified = len(verification.Persisting) == 0 && len(verification.New) == 0
+	return verification, nil
+}
+
+// handleVerifyFix handles the verify_fix tool
+func (s *MCPServer) handleVerifyFix(ctx context.Context, args map[string]interface{}) (interface{}, *MCPError) {
+	original, _ := args["original_code"].(string)
+	patched, _ := args["patched_code"].(string)
+	filePath, _ := args["file_path"].(string)
+	languageStr, _ := args["language"].(string)
+
+	findings, mcpErr := s.issuesFromArgs(ctx, args)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	if filePath == "" {
+		filePath = findings[0].FilePath
+	}
+
+	_, analyzer, mcpErr := s.resolveAnalyzer(ctx, languageStr, patched, filePath)
+	if mcpErr != nil {
+		return nil, mcpErr
+	}
+	for i := range findings {
+		if rule := analyzers.MatchRule(analyzer.GetRules(), findings[i]); rule != nil {
+			findings[i].RuleID = rule.ID
+		}
+	}
+
+	verification, err := s.verifyFix(ctx, analyzer, filePath, normalizeLineEndings(original), normalizeLineEndings(patched), findings)
+	if err != nil {
+		return nil, &MCPError{Code: -32603, Message: fmt.Sprintf("Verification failed: %v", err)}
+	}
+
+	response := map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatFixVerification(verification),
+			},
+		},
+		"structuredContent": verification,
+	}
+	return response, nil
+}
+
+// formatFixVerification formats a verification as markdown
+func formatFixVerification(verification *fixVerification) string {
+	var sb strings.Builder
+
+	sb.WriteString("# Fix Verification\n\n")
+	total := len(verification.Resolved) + len(verification.Persisting)
+	switch {
+	case verification.Verified:
+		sb.WriteString(fmt.Sprintf("The patch resolves all %d finding(s) without introducing new ones.\n\n", total))
+	case len(verification.New) == 0:
+		sb.WriteString(fmt.Sprintf("The patch resolves %d of %d finding(s).\n\n", len(verification.Resolved), total))
+	default:
+		sb.WriteString(fmt.Sprintf("The patch resolves %d of %d finding(s) and introduces %d new one(s).\n\n", len(verification.Resolved), total, len(verification.New)))
+	}
+
+	sections := []struct {
+		title  string
+		issues []models.SecurityIssue
+	}{
+		{"Resolved", verification.Resolved},
+		{"Persisting", verification.Persisting},
+		{"New", verification.New},
+	}
+	for _, section := range sections {
+		if len(section.issues) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n", section.title))
+		writeIssueList(&sb, section.issues)
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
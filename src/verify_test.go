@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+func TestLineMapper(t *testing.T) {
+	mapLine, err := lineMapper("a\nb\nc\nd\n", "x\na\nc\nd\ne\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[int]int{1: 2, 2: 3, 3: 3, 4: 4, 0: 0, 9: 9}
+	for line, expected := range tests {
+		if got := mapLine(line); got != expected {
+			t.Errorf("mapLine(%d) = %d, want %d", line, got, expected)
+		}
+	}
+}
+
+func TestVerifyFix(t *testing.T) {
+	original := "class Repo {\n  void find(String id) {\n    db.query(\"SELECT * FROM t WHERE id = \" + id);\n    MessageDigest.getInstance(\"MD5\");\n  }\n}\n"
+	patched := "import java.util.Objects;\nclass Repo {\n  void find(String id) {\n    db.query(\"SELECT * FROM t WHERE id = ?\", id);\n    MessageDigest.getInstance(\"MD5\");\n    Runtime.getRuntime().exec(id);\n  }\n}\n"
+
+	// The re-run of the original code also reports a weak hash that the
+	// caller did not pass in, so it is neither persisting nor new
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		content := `[{"title":"SQL Injection","severity":"HIGH","line_number":3},{"title":"Weak Hash","severity":"MEDIUM","line_number":4}]`
+		if strings.Contains(string(body), "WHERE id = ?") {
+			content = `[{"title":"Weak Hash","severity":"MEDIUM","line_number":5},{"title":"Command Injection","severity":"CRITICAL","line_number":6}]`
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{
+				{"message": map[string]string{"role": "assistant", "content": content}},
+			},
+		})
+	}))
+	t.Cleanup(llm.Close)
+	t.Setenv("LMSTUDIO_BASE_URL", llm.URL)
+	server := NewMCPServer()
+
+	response := callMethod(t, server, "tools/call", map[string]interface{}{
+		"name": "verify_fix",
+		"arguments": map[string]interface{}{
+			"original_code": original,
+			"patched_code":  patched,
+			"file_path":     "src/Repo.java",
+			"issues":        []interface{}{map[string]interface{}{"id": "VULN-007", "title": "SQL injection", "line_number": 3.0}},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("verify_fix failed: %s", response.Error.Message)
+	}
+
+	jsonData, _ := json.Marshal(response.Result)
+	var result struct {
+		StructuredContent struct {
+			Resolved   []struct{ Title string } `json:"resolved"`
+			Persisting []struct{ Title string } `json:"persisting"`
+			New        []struct{ Title string } `json:"new"`
+			Verified   bool                     `json:"verified"`
+		} `json:"structuredContent"`
+	}
+	json.Unmarshal(jsonData, &result)
+
+	verification := result.StructuredContent
+	if len(verification.Resolved) != 1 || verification.Resolved[0].Title != "SQL injection" {
+		t.Errorf("expected the SQL injection to be resolved, got %s", jsonData)
+	}
+	if len(verification.Persisting) != 0 {
+		t.Errorf("expected nothing persisting, got %s", jsonData)
+	}
+	if len(verification.New) != 1 || verification.New[0].Title != "Command Injection" {
+		t.Errorf("expected the command injection to be new, got %s", jsonData)
+	}
+	if verification.Verified {
+		t.Error("expected a patch introducing a finding not to verify")
+	}
+}
+
+func TestSameFinding(t *testing.T) {
+	mapLine, _ := lineMapper("a\nb\nc\n", "x\nx\nx\nx\nx\na\nb\nc\n")
+
+	tests := []struct {
+		before, after string
+		beforeLine    int
+		afterLine     int
+		expected      bool
+	}{
+		{"SQL Injection", "sql  injection", 2, 7, true},
+		{"SQL Injection", "SQL Injection", 2, 20, false},
+		{"SQL Injection", "Weak Hash", 2, 7, false},
+		{"SQL Injection", "SQL Injection", 0, 20, true},
+	}
+	for _, test := range tests {
+		before := models.SecurityIssue{Title: test.before, LineNumber: test.beforeLine}
+		after := models.SecurityIssue{Title: test.after, LineNumber: test.afterLine}
+		if got := sameFinding(before, after, mapLine); got != test.expected {
+			t.Errorf("sameFinding(%q:%d, %q:%d) = %v, want %v", test.before, test.beforeLine, test.after, test.afterLine, got, test.expected)
+		}
+	}
+}
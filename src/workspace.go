@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/emware/aeyewire-mcp/src/models"
+)
+
+// MAX_SOURCE_FILE_SIZE skips generated or minified files too large to send
+// to the LLM
+const MAX_SOURCE_FILE_SIZE = 1024 * 1024
+
+// SKIPPED_DIRS are directories never searched for source files, since they
+// hold dependencies or build output rather than the project's sources
+var SKIPPED_DIRS = map[string]bool{
+	"node_modules": true,
+	"bin":          true,
+	"obj":          true,
+	"target":       true,
+	"build":        true,
+	"dist":         true,
+	"vendor":       true,
+}
+
+// walkSourceFiles calls fn for each file under root whose extension the
+// language detector recognizes, skipping hidden, dependency and build
+// directories and anything excluded by .gitignore or .aeyewireignore files.
+// fn may return filepath.SkipAll to stop the walk early.
+func (s *MCPServer) walkSourceFiles(ctx context.Context, root string, fn func(path string) error) error {
+	ignore := newIgnoreMatcher(root, IGNORE_FILES)
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip unreadable entries rather than failing the walk
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		name := entry.Name()
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if path != root && (strings.HasPrefix(name, ".") || SKIPPED_DIRS[name] || ignore.ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			ignore.load(path)
+			return nil
+		}
+		if !entry.Type().IsRegular() || s.languageDetector.DetectFromExtension(name) == models.UNKNOWN {
+			return nil
+		}
+		if ignore.ignored(rel, false) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// readSourceFile reads a source file as UTF-8 text with LF line endings.
+// Byte order marks are stripped, UTF-16 files are decoded, and files that
+// are not valid UTF-8 are read as Windows-1252, the usual legacy encoding of
+// C# and Java sources. Line numbers are preserved.
+func readSourceFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MAX_SOURCE_FILE_SIZE {
+		return "", fmt.Errorf("%s is %d bytes, over the %d byte limit", path, info.Size(), MAX_SOURCE_FILE_SIZE)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return decodeSource(data), nil
+}
+
+// decodeSource converts raw file contents to UTF-8 with LF line endings
+func decodeSource(data []byte) string {
+	var text string
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		text = string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		text = decodeUTF16(data[2:], binary.LittleEndian)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		text = decodeUTF16(data[2:], binary.BigEndian)
+	case utf8.Valid(data):
+		text = string(data)
+	default:
+		text = decodeWindows1252(data)
+	}
+
+	return normalizeLineEndings(text)
+}
+
+// normalizeLineEndings converts CRLF and lone CR line endings to LF, so line
+// numbers match what editors display
+func normalizeLineEndings(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
+
+// decodeUTF16 decodes UTF-16 text without its byte order mark
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1. Undefined bytes map to the replacement character.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// decodeWindows1252 decodes Windows-1252 text
+func decodeWindows1252(data []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(data))
+	for _, b := range data {
+		if b >= 0x80 && b < 0xA0 {
+			sb.WriteRune(windows1252[b-0x80])
+		} else {
+			sb.WriteRune(rune(b))
+		}
+	}
+	return sb.String()
+}
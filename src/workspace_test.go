@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"utf-8", []byte("class A {}\n"), "class A {}\n"},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFclass A {}"), "class A {}"},
+		{"crlf", []byte("a\r\nb\r\n"), "a\nb\n"},
+		{"lone cr", []byte("a\rb"), "a\nb"},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 'a', 0, '\r', 0, '\n', 0, 0xE9, 0}, "a\né"},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'a', 0x20, 0xAC}, "a€"},
+		{"windows-1252", []byte("// caf\xE9 \x80\r\n"), "// café €\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if text := decodeSource(tt.data); text != tt.expected {
+				t.Errorf("decodeSource() = %q, want %q", text, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadSourceFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := readSourceFile(dir); err == nil {
+		t.Error("expected reading a directory to fail")
+	}
+
+	large := filepath.Join(dir, "Large.java")
+	os.WriteFile(large, make([]byte, MAX_SOURCE_FILE_SIZE+1), 0o644)
+	if _, err := readSourceFile(large); err == nil {
+		t.Error("expected a file over the size limit to be rejected")
+	}
+}